@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -18,32 +22,121 @@ const (
 	RunRemote = "tcp"
 )
 
+// SessionHeader names the machine a request comes from.
+const SessionHeader = "X-Gh-Rdm-Session"
+
+// PromptTimeout bounds how long the server waits for the local user to
+// answer a confirmation prompt.
+const PromptTimeout = time.Minute
+
 type Command struct {
 	Name      string   `json:"name"`
 	Arguments []string `json:"arguments"`
 }
 
 type Client struct {
-	path       string
-	httpClient http.Client
+	path         string
+	token        string
+	imageOptions string
+	httpClient   http.Client
+}
+
+// DefaultPort is the remote port the tunnel forwards to the server.
+const DefaultPort = 7391
+
+// Environment variables that override the tunnel port and the server's
+// socket path. The root command sets them from its flags and the config
+// file, so they reach every subcommand and the processes it starts.
+const (
+	PortEnv   = "GH_RDM_PORT"
+	SocketEnv = "GH_RDM_SOCKET"
+)
+
+// Port returns the remote port the tunnel forwards: $GH_RDM_PORT, the port
+// the tunnel recorded in the port file, or DefaultPort.
+func Port() string {
+	if os.Getenv(PortEnv) == "" {
+		if port := readPortFile(); port != "" {
+			return port
+		}
+	}
+	return ConfiguredPort()
+}
+
+// ConfiguredPort returns the port set by flag, environment or config file,
+// all of which end up in $GH_RDM_PORT, or DefaultPort. Unlike Port it ignores
+// the port file, which describes the remote side, so the local machine uses
+// it for the forwards it sets up.
+func ConfiguredPort() string {
+	if port := os.Getenv(PortEnv); port != "" {
+		return port
+	}
+	return strconv.Itoa(DefaultPort)
+}
+
+// readPortFile returns the port in PortPath, or "" if there is no usable one.
+func readPortFile() string {
+	path, err := PortPath()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	port := strings.TrimSpace(string(data))
+	if ValidatePort(port) != nil {
+		return ""
+	}
+	return port
 }
 
+// UnixSocketPath returns where the server listens: $GH_RDM_SOCKET, or
+// gh-rdm.sock in SocketDir.
 func UnixSocketPath() string {
-	tmp := strings.TrimRight(os.TempDir(), "/")
-	return tmp + "/gh-rdm.sock"
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path
+	}
+	return defaultSocketPath()
+}
+
+func defaultSocketPath() string {
+	return filepath.Join(SocketDir(), "gh-rdm.sock")
+}
+
+// SocketDir returns the private directory that holds the socket by default:
+// $XDG_RUNTIME_DIR/gh-rdm, or gh-rdm-<uid> in the temporary directory so
+// users sharing /tmp don't collide.
+func SocketDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gh-rdm")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gh-rdm-%d", os.Getuid()))
+}
+
+// ValidatePort checks that port is a usable TCP port number.
+func ValidatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q: use a number from 1 to 65535", port)
+	}
+	return nil
 }
 
 func New() *Client {
 	if isRemoteEnvironment() {
-		return NewWithTCPAddress("localhost:7391")
+		return NewWithTCPAddress("localhost:" + Port())
 	}
 
 	return NewWithSocketPath(UnixSocketPath())
 }
 
+// NewWithSocketPath returns a client of the server listening on socketPath,
+// presenting that server's token.
 func NewWithSocketPath(socketPath string) *Client {
 	return &Client{
-		path: "http://unix://" + socketPath,
+		path:  "http://unix://" + socketPath,
+		token: ReadToken(SocketTokenKey(socketPath)),
 		httpClient: http.Client{
 			Timeout: 10 * time.Second,
 			Transport: &http.Transport{
@@ -55,13 +148,29 @@ func NewWithSocketPath(socketPath string) *Client {
 	}
 }
 
+// NewWithTCPAddress returns a client of the tunnel at address, presenting
+// the token handed over for the configured port.
 func NewWithTCPAddress(address string) *Client {
 	return &Client{
 		path:       "http://" + address,
+		token:      ReadToken(PortTokenKey(os.Getenv(PortEnv))),
 		httpClient: http.Client{Timeout: 10 * time.Second},
 	}
 }
 
+// SetTimeout changes how long a command may take, for commands that can
+// wait on the local user. For SendStream it is how long the server may take
+// to answer once the payload is sent.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.httpClient.Timeout = d
+}
+
+// SetImageOptions asks the server to convert the images it sends, with
+// options in the query-string form ImageHeader carries.
+func (c *Client) SetImageOptions(query string) {
+	c.imageOptions = query
+}
+
 func isRemoteEnvironment() bool {
 	return os.Getenv("SSH_TTY") != "" ||
 		os.Getenv("SSH_CLIENT") != "" ||
@@ -71,6 +180,32 @@ func isRemoteEnvironment() bool {
 }
 
 func (c *Client) SendCommand(ctx context.Context, commandName string, arguments ...string) ([]byte, error) {
+	req, err := c.newRequest(ctx, commandName, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending command: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if err := checkResponse(resp, responseBody); err != nil {
+		if errors.Is(err, errUnknownCommand) {
+			return nil, c.unsupportedError(ctx, commandName)
+		}
+		return nil, err
+	}
+
+	return responseBody, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, commandName string, arguments []string) (*http.Request, error) {
 	cmd := Command{
 		Name:      commandName,
 		Arguments: arguments,
@@ -86,20 +221,66 @@ func (c *Client) SendCommand(ctx context.Context, commandName string, arguments
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("sending command: %w", err)
+	return req, nil
+}
+
+// setHeaders adds the session token, the name of this session and any image
+// options to req.
+func (c *Client) setHeaders(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
 	}
-	defer resp.Body.Close()
+	if session := SessionName(); session != "" {
+		req.Header.Set(SessionHeader, session)
+	}
+	if c.imageOptions != "" {
+		req.Header.Set(ImageHeader, c.imageOptions)
+	}
+}
 
-	responseBody, err := io.ReadAll(resp.Body)
+// SessionName identifies the machine a request comes from: the codespace
+// name when in one, the host name otherwise.
+func SessionName() string {
+	if name := os.Getenv("CODESPACE_NAME"); name != "" {
+		return name
+	}
+	hostname, err := os.Hostname()
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return ""
 	}
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return nil, fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(responseBody)))
+	return hostname
+}
+
+// errUnknownCommand marks a server's "unknown command" rejection so callers
+// can explain the version skew behind it.
+var errUnknownCommand = errors.New("unknown command")
+
+// IsUnreachable reports whether err means no server answered: nothing is
+// listening, or the tunnel dropped the connection before a response.
+// Errors the server itself returned are not included.
+func IsUnreachable(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
 	}
+	// An SSH remote forward accepts the connection and then closes it when
+	// the local end is down.
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
 
-	return responseBody, nil
+// checkResponse turns a non-2xx response into an error. body is the already
+// read response body.
+func checkResponse(resp *http.Response, body []byte) error {
+	if resp.StatusCode == http.StatusBadRequest && bytes.HasPrefix(body, []byte("unknown command")) {
+		return fmt.Errorf("%w: %s", errUnknownCommand, strings.TrimSpace(string(body)))
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%w; restart the tunnel or copy the output of `gh rdm token` on your local machine to ~/.gh-rdm/token", ErrUnauthorized)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
 }
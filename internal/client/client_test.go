@@ -3,20 +3,57 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestUnixSocketPath(t *testing.T) {
-	tmp := strings.TrimRight(os.TempDir(), "/")
-	expected := tmp + "/gh-rdm.sock"
-	got := UnixSocketPath()
-	if got != expected {
-		t.Errorf("UnixSocketPath() = %q, want %q", got, expected)
+	t.Setenv(SocketEnv, "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	if got, want := UnixSocketPath(), "/run/user/1000/gh-rdm/gh-rdm.sock"; got != want {
+		t.Errorf("UnixSocketPath() = %q, want %q", got, want)
+	}
+}
+
+func TestUnixSocketPathWithoutRuntimeDir(t *testing.T) {
+	t.Setenv(SocketEnv, "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	want := filepath.Join(os.TempDir(), fmt.Sprintf("gh-rdm-%d", os.Getuid()), "gh-rdm.sock")
+	if got := UnixSocketPath(); got != want {
+		t.Errorf("UnixSocketPath() = %q, want %q", got, want)
+	}
+}
+
+func TestUnixSocketPathFromEnvironment(t *testing.T) {
+	t.Setenv(SocketEnv, "/run/user/1000/rdm.sock")
+
+	if got := UnixSocketPath(); got != "/run/user/1000/rdm.sock" {
+		t.Errorf("UnixSocketPath() = %q, want $%s", got, SocketEnv)
+	}
+}
+
+func TestValidatePort(t *testing.T) {
+	for _, port := range []string{"1", "7391", "65535"} {
+		if err := ValidatePort(port); err != nil {
+			t.Errorf("ValidatePort(%q) = %v, want nil", port, err)
+		}
+	}
+	for _, port := range []string{"", "0", "65536", "http", "-1"} {
+		if err := ValidatePort(port); err == nil {
+			t.Errorf("ValidatePort(%q) = nil, want error", port)
+		}
 	}
 }
 
@@ -80,9 +117,35 @@ func TestSendCommandHTTPError(t *testing.T) {
 	if !strings.Contains(err.Error(), "500 Internal Server Error") {
 		t.Fatalf("SendCommand() error = %q, want status", err)
 	}
+	if IsUnreachable(err) {
+		t.Fatal("IsUnreachable() = true for a server error")
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	c := NewWithSocketPath(filepath.Join(t.TempDir(), "missing.sock"))
+	_, err := c.SendCommand(context.Background(), "copy", "hello")
+	if !IsUnreachable(err) {
+		t.Fatalf("IsUnreachable(%v) = false, want true", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Drop the connection without answering, like a tunnel with no
+		// local server behind it.
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer ts.Close()
+
+	c = &Client{path: ts.URL, httpClient: *ts.Client()}
+	_, err = c.SendCommand(context.Background(), "copy", "hello")
+	if !IsUnreachable(err) {
+		t.Fatalf("IsUnreachable(%v) = false, want true", err)
+	}
 }
 
 func TestNewUsesTCPInSSHEnvironment(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	t.Setenv("SSH_TTY", "/dev/pts/1")
 
 	c := New()
@@ -92,7 +155,75 @@ func TestNewUsesTCPInSSHEnvironment(t *testing.T) {
 	}
 }
 
+func TestNewUsesPortFromEnvironment(t *testing.T) {
+	t.Setenv("SSH_TTY", "/dev/pts/1")
+	t.Setenv(PortEnv, "8123")
+
+	c := New()
+
+	if c.path != "http://localhost:8123" {
+		t.Fatalf("New() path = %q, want $%s port", c.path, PortEnv)
+	}
+}
+
+func TestNewUsesPortFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_TTY", "/dev/pts/1")
+	if err := os.MkdirAll(filepath.Join(home, ".gh-rdm"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gh-rdm", "port"), []byte("24817\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := New(); c.path != "http://localhost:24817" {
+		t.Fatalf("New() path = %q, want port from port file", c.path)
+	}
+
+	t.Setenv(PortEnv, "8123")
+	if c := New(); c.path != "http://localhost:8123" {
+		t.Fatalf("New() path = %q, want $%s to win over the port file", c.path, PortEnv)
+	}
+}
+
+func TestConfiguredPortIgnoresPortFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(PortEnv, "")
+	if err := os.MkdirAll(filepath.Join(home, ".gh-rdm"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gh-rdm", "port"), []byte("24817\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ConfiguredPort(); got != "7391" {
+		t.Fatalf("ConfiguredPort() = %q, want the default", got)
+	}
+	t.Setenv(PortEnv, "8123")
+	if got := ConfiguredPort(); got != "8123" {
+		t.Fatalf("ConfiguredPort() = %q, want $%s", got, PortEnv)
+	}
+}
+
+func TestPortIgnoresInvalidPortFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".gh-rdm"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gh-rdm", "port"), []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Port(); got != "7391" {
+		t.Fatalf("Port() = %q, want the default", got)
+	}
+}
+
 func TestNewUsesTCPInCodespaceEnvironment(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	t.Setenv("CODESPACES", "true")
 
 	c := New()
@@ -114,3 +245,372 @@ func TestNewWithSocketPathIgnoresSSHEnvironment(t *testing.T) {
 		t.Fatal("NewWithSocketPath() transport = nil, want unix transport")
 	}
 }
+
+func TestSendCommandSendsToken(t *testing.T) {
+	var authorization string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		token:      "secret",
+		httpClient: *ts.Client(),
+	}
+
+	if _, err := c.SendCommand(context.Background(), "status"); err != nil {
+		t.Fatalf("SendCommand() error: %v", err)
+	}
+	if authorization != "Bearer secret" {
+		t.Fatalf("Authorization header = %q, want %q", authorization, "Bearer secret")
+	}
+}
+
+func TestSendCommandUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		httpClient: *ts.Client(),
+	}
+
+	_, err := c.SendCommand(context.Background(), "paste")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("SendCommand() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestReadTokenPrefersEnvironment(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(TokenEnv, "from-env")
+
+	if err := WriteToken("", "from-file"); err != nil {
+		t.Fatalf("WriteToken() error: %v", err)
+	}
+
+	if got := ReadToken(""); got != "from-env" {
+		t.Fatalf("ReadToken() = %q, want %q", got, "from-env")
+	}
+}
+
+func TestTokenFilesAreKeyedBySocket(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv(TokenEnv, "")
+	t.Setenv(SocketEnv, "")
+	work := filepath.Join(t.TempDir(), "work.sock")
+
+	if err := WriteToken(SocketTokenKey(UnixSocketPath()), "default-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteToken(SocketTokenKey(work), "work-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := NewWithSocketPath(UnixSocketPath()).token; got != "default-token" {
+		t.Fatalf("default socket client token = %q, want default-token", got)
+	}
+	if got := NewWithSocketPath(work).token; got != "work-token" {
+		t.Fatalf("work socket client token = %q, want work-token", got)
+	}
+}
+
+func TestPortTokenKey(t *testing.T) {
+	for port, want := range map[string]string{"": "", "7391": "", "7392": "7392"} {
+		if got := PortTokenKey(port); got != want {
+			t.Errorf("PortTokenKey(%q) = %q, want %q", port, got, want)
+		}
+	}
+}
+
+func TestWriteTokenIsPrivate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(TokenEnv, "")
+
+	if err := WriteToken("", "from-file"); err != nil {
+		t.Fatalf("WriteToken() error: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".gh-rdm", "token"))
+	if err != nil {
+		t.Fatalf("stat token: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("token mode = %v, want 0600", info.Mode().Perm())
+	}
+	if got := ReadToken(""); got != "from-file" {
+		t.Fatalf("ReadToken() = %q, want %q", got, "from-file")
+	}
+}
+
+func TestFetchStreamReadsRawBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), StreamContentType) {
+			t.Errorf("Accept = %q, want %q", r.Header.Get("Accept"), StreamContentType)
+		}
+		w.Header().Set("Content-Type", StreamContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="shot.png"`)
+		w.Write([]byte("raw-png"))
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		httpClient: *ts.Client(),
+	}
+
+	stream, err := c.FetchStream(context.Background(), "screenshot")
+	if err != nil {
+		t.Fatalf("FetchStream() error: %v", err)
+	}
+	defer stream.Body.Close()
+
+	data, err := io.ReadAll(stream.Body)
+	if err != nil {
+		t.Fatalf("reading stream: %v", err)
+	}
+	if string(data) != "raw-png" {
+		t.Fatalf("stream body = %q, want %q", data, "raw-png")
+	}
+	if stream.Filename != "shot.png" {
+		t.Fatalf("stream filename = %q, want %q", stream.Filename, "shot.png")
+	}
+	if stream.Size != int64(len("raw-png")) {
+		t.Fatalf("stream size = %d, want %d", stream.Size, len("raw-png"))
+	}
+}
+
+func TestFetchStreamDecodesLegacyJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"filename":"clipboard.png","data":"bGVnYWN5"}`))
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		httpClient: *ts.Client(),
+	}
+
+	stream, err := c.FetchStream(context.Background(), "clipboard-image")
+	if err != nil {
+		t.Fatalf("FetchStream() error: %v", err)
+	}
+	defer stream.Body.Close()
+
+	data, _ := io.ReadAll(stream.Body)
+	if string(data) != "legacy" {
+		t.Fatalf("stream body = %q, want %q", data, "legacy")
+	}
+	if stream.Filename != "clipboard.png" {
+		t.Fatalf("stream filename = %q, want %q", stream.Filename, "clipboard.png")
+	}
+}
+
+func TestFetchStreamHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no image on clipboard", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		httpClient: *ts.Client(),
+	}
+
+	_, err := c.FetchStream(context.Background(), "clipboard-image")
+	if err == nil || !strings.Contains(err.Error(), "no image on clipboard") {
+		t.Fatalf("FetchStream() error = %v, want server message", err)
+	}
+}
+
+func TestSendCommandExplainsVersionSkew(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmd Command
+		json.NewDecoder(r.Body).Decode(&cmd)
+		if cmd.Name == "status" {
+			w.Write([]byte(`{"status":"running","version":"v0.4.0","protocol_version":1,"commands":["status","copy"]}`))
+			return
+		}
+		http.Error(w, "unknown command: "+cmd.Name, http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		httpClient: *ts.Client(),
+	}
+
+	_, err := c.SendCommand(context.Background(), "notify")
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("SendCommand() error = %v, want ErrUnsupported", err)
+	}
+	if !strings.Contains(err.Error(), "version v0.4.0") || !strings.Contains(err.Error(), "upgrade gh-rdm on your local machine") {
+		t.Fatalf("SendCommand() error = %q, want version skew hint", err)
+	}
+}
+
+func TestStatusCheckProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  Status
+		wantErr string
+	}{
+		{"matching", Status{ProtocolVersion: ProtocolVersion}, ""},
+		{"legacy server", Status{}, "predates protocol versioning"},
+		{"newer server", Status{Version: "v9.0.0", ProtocolVersion: ProtocolVersion + 1}, "upgrade gh-rdm on this machine"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.status.CheckProtocol()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckProtocol() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckProtocol() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusRequireFeature(t *testing.T) {
+	status := Status{Version: "v1.2.0", Features: []string{FeatureStream}}
+
+	if err := status.RequireFeature(FeatureStream, "streaming"); err != nil {
+		t.Fatalf("RequireFeature() error = %v, want nil", err)
+	}
+	err := status.RequireFeature(FeatureScreenshotSelect, "listing and selecting screenshots")
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "version v1.2.0") {
+		t.Fatalf("RequireFeature() error = %v, want ErrUnsupported naming the version", err)
+	}
+}
+
+func TestFetchFilesReadsEachPart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		for _, name := range []string{"one.png", "two.png"} {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", `attachment; filename="`+name+`"`)
+			header.Set("Content-Length", "3")
+			header.Set("Last-Modified", "Sat, 17 Oct 2026 09:41:12 GMT")
+			part, _ := mw.CreatePart(header)
+			part.Write([]byte(name[:3]))
+		}
+		mw.Close()
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		httpClient: *ts.Client(),
+	}
+
+	var got []string
+	err := c.FetchFiles(context.Background(), "screenshots", func(s *Stream) error {
+		data, err := io.ReadAll(s.Body)
+		if err != nil {
+			return err
+		}
+		if s.Size != 3 || s.ModTime.IsZero() {
+			t.Errorf("stream %s: size %d, mod time %v", s.Filename, s.Size, s.ModTime)
+		}
+		got = append(got, s.Filename+"="+string(data))
+		return nil
+	}, "last", "2")
+	if err != nil {
+		t.Fatalf("FetchFiles() error: %v", err)
+	}
+	if want := []string{"one.png=one", "two.png=two"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("FetchFiles() parts = %v, want %v", got, want)
+	}
+}
+
+func TestFetchFilesHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no matching screenshots", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		path:       ts.URL,
+		httpClient: *ts.Client(),
+	}
+
+	err := c.FetchFiles(context.Background(), "screenshots", func(*Stream) error {
+		t.Fatal("fn called for an error response")
+		return nil
+	}, "since", "10m")
+	if err == nil || !strings.Contains(err.Error(), "no matching screenshots") {
+		t.Fatalf("FetchFiles() error = %v, want the server's message", err)
+	}
+}
+
+func TestFetchStreamReportsStall(t *testing.T) {
+	defer func(d time.Duration) { IdleTimeout = d }(IdleTimeout)
+	IdleTimeout = 50 * time.Millisecond
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", StreamContentType)
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	c := &Client{path: ts.URL, httpClient: *ts.Client()}
+	stream, err := c.FetchStream(context.Background(), "screenshot")
+	if err != nil {
+		t.Fatalf("FetchStream() error: %v", err)
+	}
+	defer stream.Body.Close()
+
+	if _, err := io.ReadAll(stream.Body); !errors.Is(err, ErrStalled) {
+		t.Fatalf("reading a stalled stream: error = %v, want ErrStalled", err)
+	}
+}
+
+func TestFetchStreamAllowsSlowProgress(t *testing.T) {
+	defer func(d time.Duration) { IdleTimeout = d }(IdleTimeout)
+	IdleTimeout = 50 * time.Millisecond
+
+	const chunks = 6
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", StreamContentType)
+		for range chunks {
+			w.Write([]byte("x"))
+			w.(http.Flusher).Flush()
+			time.Sleep(IdleTimeout / 2)
+		}
+	}))
+	defer ts.Close()
+
+	c := &Client{path: ts.URL, httpClient: *ts.Client()}
+	start := time.Now()
+	stream, err := c.FetchStream(context.Background(), "screenshot")
+	if err != nil {
+		t.Fatalf("FetchStream() error: %v", err)
+	}
+	defer stream.Body.Close()
+
+	data, err := io.ReadAll(stream.Body)
+	if err != nil {
+		t.Fatalf("reading a slow stream: %v", err)
+	}
+	if len(data) != chunks {
+		t.Fatalf("stream body = %q, want %d bytes", data, chunks)
+	}
+	if elapsed := time.Since(start); elapsed <= IdleTimeout {
+		t.Fatalf("transfer took %s, want longer than the idle timeout %s", elapsed, IdleTimeout)
+	}
+}
@@ -0,0 +1,11 @@
+package client
+
+import "time"
+
+// HistoryEntry is one clipboard copy remembered by the server, as listed by
+// the "history" command.
+type HistoryEntry struct {
+	Text    string    `json:"text"`
+	Time    time.Time `json:"time"`
+	Session string    `json:"session,omitempty"`
+}
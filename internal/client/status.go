@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ProtocolVersion is bumped whenever the request or response format changes
+// in a way older peers can't handle.
+const ProtocolVersion = 1
+
+// Features advertised in Status.Features.
+const (
+	FeatureTokenAuth = "token-auth"
+	FeatureStream    = "stream"
+	// FeatureMIMEClipboard means copy accepts a streamed payload with a MIME
+	// type and paste takes a MIME type argument.
+	FeatureMIMEClipboard = "mime-clipboard"
+	// FeatureScreenshotSelect means the screenshots command lists
+	// screenshots and sends them by count, age or name.
+	FeatureScreenshotSelect = "screenshot-select"
+	// FeatureScreenshotWatch means the screenshots command can stream new
+	// screenshots as they are taken.
+	FeatureScreenshotWatch = "screenshot-watch"
+	// FeatureImageConvert means screenshots and clipboard images honour
+	// ImageHeader.
+	FeatureImageConvert = "image-convert"
+)
+
+// ErrUnsupported is returned when the server doesn't know a command.
+var ErrUnsupported = errors.New("command not supported by the gh-rdm server")
+
+// Status describes a running server. Servers that predate version reporting
+// only fill in Status.
+type Status struct {
+	Status          string   `json:"status"`
+	Version         string   `json:"version,omitempty"`
+	ProtocolVersion int      `json:"protocol_version,omitempty"`
+	OS              string   `json:"os,omitempty"`
+	Arch            string   `json:"arch,omitempty"`
+	Commands        []string `json:"commands,omitempty"`
+	Features        []string `json:"features,omitempty"`
+}
+
+// Supports reports whether the server handles command.
+func (s *Status) Supports(command string) bool {
+	return slices.Contains(s.Commands, command)
+}
+
+// HasFeature reports whether the server advertises feature.
+func (s *Status) HasFeature(feature string) bool {
+	return slices.Contains(s.Features, feature)
+}
+
+// RequireFeature returns an ErrUnsupported error pointing at the server
+// upgrade when the server doesn't advertise feature. what describes the
+// feature to the user.
+func (s *Status) RequireFeature(feature, what string) error {
+	if s.HasFeature(feature) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not available in the local gh-rdm server (%s); upgrade gh-rdm on your local machine and restart the server", ErrUnsupported, what, s.describeVersion())
+}
+
+// CheckProtocol returns an error describing the skew when the server speaks a
+// different protocol version than this client.
+func (s *Status) CheckProtocol() error {
+	switch {
+	case s.ProtocolVersion == ProtocolVersion:
+		return nil
+	case s.ProtocolVersion == 0:
+		return fmt.Errorf("the local gh-rdm server predates protocol versioning; upgrade gh-rdm on your local machine and restart the server")
+	case s.ProtocolVersion < ProtocolVersion:
+		return fmt.Errorf("the local gh-rdm server (%s) speaks protocol %d but this client needs %d; upgrade gh-rdm on your local machine and restart the server", s.describeVersion(), s.ProtocolVersion, ProtocolVersion)
+	default:
+		return fmt.Errorf("the local gh-rdm server (%s) speaks protocol %d but this client only knows %d; upgrade gh-rdm on this machine", s.describeVersion(), s.ProtocolVersion, ProtocolVersion)
+	}
+}
+
+func (s *Status) describeVersion() string {
+	if s.Version == "" {
+		return "unknown version"
+	}
+	return "version " + s.Version
+}
+
+// Status asks the server to describe itself.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	data, err := c.SendCommand(ctx, "status")
+	if err != nil {
+		return nil, err
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("parsing status response: %w", err)
+	}
+	return &status, nil
+}
+
+// RequireFeature asks the server for its status and checks that it
+// advertises feature, as Status.RequireFeature does.
+func (c *Client) RequireFeature(ctx context.Context, feature, what string) error {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return status.RequireFeature(feature, what)
+}
+
+// unsupportedError explains why the server rejected commandName, asking it
+// for its version so the message can point at the side that needs upgrading.
+func (c *Client) unsupportedError(ctx context.Context, commandName string) error {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrUnsupported, commandName)
+	}
+	if err := status.CheckProtocol(); err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrUnsupported, commandName, err)
+	}
+	return fmt.Errorf("%w: %q is not available in the local gh-rdm server (%s); upgrade gh-rdm on your local machine and restart the server", ErrUnsupported, commandName, status.describeVersion())
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// StreamContentType marks raw binary request and response bodies.
+	StreamContentType = "application/octet-stream"
+
+	// MultipartContentType marks responses carrying several files, one
+	// per part.
+	MultipartContentType = "multipart/mixed"
+
+	// HeartbeatHeader marks empty multipart parts a server sends to show a
+	// long-lived response is still alive. FetchFiles skips them.
+	HeartbeatHeader = "X-Gh-Rdm-Heartbeat"
+
+	// ImageHeader carries image conversion options for screenshots and
+	// clipboard images as a query string, e.g. "max-width=1600&format=jpeg".
+	ImageHeader = "X-Gh-Rdm-Image"
+
+	// OriginalSizeHeader reports an image's size in bytes before the server
+	// converted it.
+	OriginalSizeHeader = "X-Gh-Rdm-Original-Size"
+
+	// CommandHeader carries the JSON-encoded Command of a streamed request,
+	// whose body is the raw payload.
+	CommandHeader = "X-Gh-Rdm-Command"
+)
+
+// IdleTimeout bounds how long a transfer may go without making progress.
+// Unlike a fixed deadline it lets large payloads through slow tunnels. Tests
+// shorten it.
+var IdleTimeout = 10 * time.Second
+
+// ErrStalled is returned when a transfer makes no progress for IdleTimeout.
+var ErrStalled = errors.New("transfer stalled")
+
+// Stream is a binary payload received from the server.
+type Stream struct {
+	Filename string
+	// Size is the payload length in bytes, or -1 when unknown.
+	Size int64
+	// ModTime is when the file was last modified, if the server said.
+	ModTime time.Time
+	// OriginalSize is the size before the server resized or re-encoded
+	// the file, or 0 when it sent the file as is.
+	OriginalSize int64
+	Body         io.ReadCloser
+}
+
+// FetchStream sends a command and asks for a raw binary response. Servers that
+// predate streaming answer with the JSON {filename, data} form, which is
+// decoded transparently. The caller must close the returned Body.
+func (c *Client) FetchStream(ctx context.Context, commandName string, arguments ...string) (*Stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	watchdog := newWatchdog(cancel)
+
+	req, err := c.newRequest(ctx, commandName, arguments)
+	if err != nil {
+		watchdog.stop()
+		return nil, err
+	}
+	req.Header.Set("Accept", StreamContentType+", application/json;q=0.5")
+
+	// The per-request Timeout would cap the whole transfer; the watchdog
+	// replaces it with an idle timeout.
+	httpClient := c.httpClient
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		watchdog.stop()
+		return nil, watchdog.wrap(fmt.Errorf("sending command: %w", err))
+	}
+
+	body := &progressBody{progressReader{r: resp.Body, watchdog: watchdog}, resp.Body}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices || mediaType != StreamContentType {
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+		if err := checkResponse(resp, data); err != nil {
+			if errors.Is(err, errUnknownCommand) {
+				return nil, c.unsupportedError(ctx, commandName)
+			}
+			return nil, err
+		}
+		return decodeLegacyStream(data)
+	}
+
+	stream := &Stream{Size: resp.ContentLength, OriginalSize: originalSize(resp.Header), Body: body}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		stream.Filename = params["filename"]
+	}
+	return stream, nil
+}
+
+// FetchFiles sends a command whose response carries several files and calls
+// fn with each one in turn. Each Body is only valid until fn returns. Like
+// FetchStream, the transfer only times out when it stops making progress.
+func (c *Client) FetchFiles(ctx context.Context, commandName string, fn func(*Stream) error, arguments ...string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	watchdog := newWatchdog(cancel)
+	defer watchdog.stop()
+
+	req, err := c.newRequest(ctx, commandName, arguments)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", MultipartContentType)
+
+	httpClient := c.httpClient
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return watchdog.wrap(fmt.Errorf("sending command: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body := &progressReader{r: resp.Body, watchdog: watchdog}
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices || mediaType != MultipartContentType {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("reading response: %w", err)
+		}
+		if err := checkResponse(resp, data); err != nil {
+			if errors.Is(err, errUnknownCommand) {
+				return c.unsupportedError(ctx, commandName)
+			}
+			return err
+		}
+		return fmt.Errorf("unexpected response type %q", resp.Header.Get("Content-Type"))
+	}
+
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading response: %w", watchdog.wrap(err))
+		}
+		if part.Header.Get(HeartbeatHeader) != "" {
+			continue
+		}
+
+		stream := &Stream{Filename: part.FileName(), Size: -1, OriginalSize: originalSize(http.Header(part.Header)), Body: part}
+		if size, err := strconv.ParseInt(part.Header.Get("Content-Length"), 10, 64); err == nil {
+			stream.Size = size
+		}
+		if modTime, err := http.ParseTime(part.Header.Get("Last-Modified")); err == nil {
+			stream.ModTime = modTime
+		}
+		if err := fn(stream); err != nil {
+			return err
+		}
+	}
+}
+
+// SendResult is the server's answer to "send": where the file was saved and,
+// when the reveal or open that followed did not happen, why not. The file is
+// saved either way.
+type SendResult struct {
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"`
+}
+
+// SendStream sends a command with body as its raw payload, for uploads that
+// are too large or too binary for a JSON argument. size is the payload length,
+// or -1 when unknown. Like FetchStream, the transfer only times out when it
+// stops making progress, except that once the payload is sent the server gets
+// the longer of IdleTimeout and the SetTimeout duration to answer.
+func (c *Client) SendStream(ctx context.Context, commandName string, body io.Reader, size int64, arguments ...string) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	watchdog := newWatchdog(cancel)
+	defer watchdog.stop()
+
+	header, err := json.Marshal(Command{Name: commandName, Arguments: arguments})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling command: %w", err)
+	}
+
+	upload := &progressReader{r: body, watchdog: watchdog, done: func() {
+		// The server may wait on the local user before it answers.
+		watchdog.extend(c.httpClient.Timeout)
+	}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.path, upload)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", StreamContentType)
+	req.Header.Set(CommandHeader, string(header))
+	c.setHeaders(req)
+
+	httpClient := c.httpClient
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, watchdog.wrap(fmt.Errorf("sending command: %w", err))
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(&progressReader{r: resp.Body, watchdog: watchdog})
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if err := checkResponse(resp, responseBody); err != nil {
+		if errors.Is(err, errUnknownCommand) {
+			return nil, c.unsupportedError(ctx, commandName)
+		}
+		return nil, err
+	}
+
+	return responseBody, nil
+}
+
+// originalSize reads OriginalSizeHeader, returning 0 when it is absent.
+func originalSize(header http.Header) int64 {
+	size, _ := strconv.ParseInt(header.Get(OriginalSizeHeader), 10, 64)
+	return size
+}
+
+// decodeLegacyStream decodes the base64 JSON body older servers send.
+func decodeLegacyStream(data []byte) (*Stream, error) {
+	var resp struct {
+		Filename string `json:"filename"`
+		Data     string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w\nraw: %s", err, strings.TrimSpace(string(data)))
+	}
+	decoded, err := base64.StdEncoding.DecodeString(resp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+	return &Stream{
+		Filename: resp.Filename,
+		Size:     int64(len(decoded)),
+		Body:     io.NopCloser(bytes.NewReader(decoded)),
+	}, nil
+}
+
+// watchdog cancels a transfer that goes IdleTimeout, or the longer period
+// extend set, without progress.
+type watchdog struct {
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	idle    atomic.Int64
+	stalled atomic.Bool
+}
+
+func newWatchdog(cancel context.CancelFunc) *watchdog {
+	w := &watchdog{cancel: cancel}
+	w.idle.Store(int64(IdleTimeout))
+	w.timer = time.AfterFunc(IdleTimeout, func() {
+		w.stalled.Store(true)
+		cancel()
+	})
+	return w
+}
+
+func (w *watchdog) reset() {
+	w.timer.Reset(time.Duration(w.idle.Load()))
+}
+
+// extend allows the transfer to go d without progress from now on, if that
+// is longer than it already may.
+func (w *watchdog) extend(d time.Duration) {
+	if int64(d) > w.idle.Load() {
+		w.idle.Store(int64(d))
+	}
+	w.reset()
+}
+
+func (w *watchdog) stop() {
+	w.timer.Stop()
+	w.cancel()
+}
+
+// wrap reports err as a stall when the watchdog caused it.
+func (w *watchdog) wrap(err error) error {
+	if err != nil && w.stalled.Load() {
+		return fmt.Errorf("%w: no data received for %s", ErrStalled, time.Duration(w.idle.Load()))
+	}
+	return err
+}
+
+// progressReader resets its watchdog whenever data moves through it, and
+// calls done, if set, when it reaches the end.
+type progressReader struct {
+	r        io.Reader
+	watchdog *watchdog
+	done     func()
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.watchdog.reset()
+	}
+	if err == io.EOF && p.done != nil {
+		p.done()
+	} else if err != nil && err != io.EOF {
+		err = p.watchdog.wrap(err)
+	}
+	return n, err
+}
+
+// progressBody is a response body guarded by a watchdog, which it stops on
+// Close.
+type progressBody struct {
+	progressReader
+	closer io.Closer
+}
+
+func (p *progressBody) Close() error {
+	p.watchdog.stop()
+	return p.closer.Close()
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TokenEnv overrides the token file when set, which is handy when the remote
+// home directory is not writable.
+const TokenEnv = "GH_RDM_TOKEN"
+
+// StateDir returns the per-user directory gh-rdm keeps its state in.
+func StateDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".gh-rdm"), nil
+}
+
+// TokenPath returns the session token file for key, as returned by
+// SocketTokenKey or PortTokenKey: token for the default setup and
+// token-<key> for one on another socket or port, so that setups running side
+// by side don't overwrite each other's token.
+func TokenPath(key string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return filepath.Join(dir, "token"), nil
+	}
+	return filepath.Join(dir, "token-"+key), nil
+}
+
+// SocketTokenKey returns the token key of the server listening on
+// socketPath: empty for the default socket, a hash of the path otherwise.
+func SocketTokenKey(socketPath string) string {
+	if socketPath == defaultSocketPath() {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(socketPath))
+	return hex.EncodeToString(sum[:6])
+}
+
+// PortTokenKey returns the token key of remote clients connecting on port:
+// empty for the default port, or when none is configured, and the port
+// itself for any other.
+func PortTokenKey(port string) string {
+	if port == "" || port == strconv.Itoa(DefaultPort) {
+		return ""
+	}
+	return port
+}
+
+// PortPath returns the file the tunnel records its remote port in when it
+// hands over the token, so clients on a shared host find their own forward.
+func PortPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "port"), nil
+}
+
+// NewToken mints a random session token.
+func NewToken() string {
+	return rand.Text()
+}
+
+// ReadToken returns the session token from GH_RDM_TOKEN or the token file
+// for key. A missing token is not an error; the server will reject the
+// request instead.
+func ReadToken(key string) string {
+	if token := strings.TrimSpace(os.Getenv(TokenEnv)); token != "" {
+		return token
+	}
+	path, err := TokenPath(key)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// WriteToken stores token in the token file for key, readable only by the
+// current user.
+func WriteToken(key, token string) error {
+	path, err := TokenPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".token-*")
+	if err != nil {
+		return fmt.Errorf("create token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("chmod token file: %w", err)
+	}
+	if _, err := tmp.WriteString(token + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write token file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("install token file: %w", err)
+	}
+	return nil
+}
+
+// RemoveToken deletes the token file for key if it still holds token, so a
+// server that stops cleans up after itself without touching a newer
+// server's token.
+func RemoveToken(key, token string) error {
+	path, err := TokenPath(key)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(data)) != token {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// ErrUnauthorized is returned when the server rejects the session token.
+var ErrUnauthorized = errors.New("server rejected the gh-rdm session token")
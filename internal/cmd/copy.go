@@ -1,26 +1,85 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
 	"os"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/osc52"
 	"github.com/spf13/cobra"
 )
 
+// Ways copy can reach the clipboard.
+const (
+	viaAuto   = "auto"
+	viaServer = "server"
+	viaOSC52  = "osc52"
+)
+
 func newCopyCmd() *cobra.Command {
-	return &cobra.Command{
+	var mimeType string
+	var via string
+
+	cmd := &cobra.Command{
 		Use:   "copy",
 		Short: "Copy stdin content to clipboard",
+		Long: `Copy stdin to the local clipboard.
+
+Use --type to copy rich content, e.g. --type text/html for a rendered table or
+--type image/png for an image.
+
+When the gh-rdm server can't be reached, plain text is copied with an OSC 52
+escape sequence written to the terminal instead, which most terminals turn into
+a clipboard update. Use --via osc52 to always do that, or --via server to never
+fall back.`,
+		Example: `  echo "hello" | gh rdm copy
+  pandoc -t html table.md | gh rdm copy --type text/html
+  gh rdm copy --type image/png < chart.png
+  echo "hello" | gh rdm copy --via osc52`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			richType := mimeType != "" && mimeType != "text/plain"
+			switch via {
+			case viaAuto, viaServer:
+			case viaOSC52:
+				if richType {
+					return fmt.Errorf("OSC 52 can only copy text/plain, not %s", mimeType)
+				}
+			default:
+				return fmt.Errorf("invalid --via %q: use %s, %s or %s", via, viaAuto, viaServer, viaOSC52)
+			}
+
+			c := client.New()
+			if richType {
+				if err := c.RequireFeature(cmd.Context(), client.FeatureMIMEClipboard, "copying with --type"); err != nil {
+					return err
+				}
+				_, err := c.SendStream(cmd.Context(), "copy", os.Stdin, -1, mimeType)
+				return err
+			}
+
 			data, err := io.ReadAll(os.Stdin)
 			if err != nil {
 				return err
 			}
+			if via == viaOSC52 {
+				return osc52.Copy(string(data))
+			}
 
-			c := client.New()
 			_, err = c.SendCommand(cmd.Context(), "copy", string(data))
+			if via == viaAuto && client.IsUnreachable(err) {
+				if oscErr := osc52.Copy(string(data)); oscErr != nil {
+					return fmt.Errorf("%w; OSC 52 fallback failed: %v", err, oscErr)
+				}
+				fmt.Fprintln(cmd.ErrOrStderr(), "⚠️  gh-rdm server unreachable; copied with an OSC 52 terminal escape instead")
+				return nil
+			}
 			return err
 		},
 	}
+
+	cmd.Flags().StringVarP(&mimeType, "type", "t", "", "MIME type of the content, e.g. text/html or image/png")
+	cmd.Flags().StringVar(&via, "via", viaAuto, "How to reach the clipboard: auto, server or osc52")
+
+	return cmd
 }
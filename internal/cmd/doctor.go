@@ -2,24 +2,25 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/config"
+	"github.com/maxbeizer/gh-rdm/internal/hostservice"
+	"github.com/maxbeizer/gh-rdm/internal/version"
 	"github.com/spf13/cobra"
 )
 
-const rdmTunnelPort = "7391"
-
 type doctorDeps struct {
-	socketPath func() string
-	statSocket func(string) error
-	statusUnix func(context.Context, string) error
-	statusTCP  func(context.Context, string) error
-	getenv     func(string) string
+	socketPath       func() string
+	statSocket       func(string) error
+	statusUnix       func(context.Context, string) (*client.Status, error)
+	statusTCP        func(context.Context, string) (*client.Status, error)
+	clipboardBackend func() (string, error)
+	getenv           func(string) string
 }
 
 func newDoctorCmd() *cobra.Command {
@@ -45,13 +46,14 @@ func defaultDoctorDeps() doctorDeps {
 			}
 			return nil
 		},
-		statusUnix: func(ctx context.Context, socketPath string) error {
-			return checkStatus(ctx, client.NewWithSocketPath(socketPath))
+		statusUnix: func(ctx context.Context, socketPath string) (*client.Status, error) {
+			return fetchStatus(ctx, client.NewWithSocketPath(socketPath))
 		},
-		statusTCP: func(ctx context.Context, address string) error {
-			return checkStatus(ctx, client.NewWithTCPAddress(address))
+		statusTCP: func(ctx context.Context, address string) (*client.Status, error) {
+			return fetchStatus(ctx, client.NewWithTCPAddress(address))
 		},
-		getenv: os.Getenv,
+		clipboardBackend: clipboardBackend,
+		getenv:           os.Getenv,
 	}
 }
 
@@ -59,6 +61,13 @@ func runDoctor(ctx context.Context, out io.Writer, deps doctorDeps) error {
 	socketPath := deps.socketPath()
 	failures := 0
 	remote := isRemoteEnvironment(deps.getenv)
+	var status *client.Status
+	recordStatus := func(s *client.Status, err error) error {
+		if err == nil && status == nil {
+			status = s
+		}
+		return err
+	}
 
 	fmt.Fprintln(out, "gh-rdm doctor")
 	fmt.Fprintln(out)
@@ -69,7 +78,14 @@ func runDoctor(ctx context.Context, out io.Writer, deps doctorDeps) error {
 		if printCheck(out, "socket path exists", socketPath, deps.statSocket(socketPath)) {
 			failures++
 		}
-		if printCheck(out, "server responds over unix socket", socketPath, deps.statusUnix(ctx, socketPath)) {
+		if printCheck(out, "server responds over unix socket", socketPath, recordStatus(deps.statusUnix(ctx, socketPath))) {
+			failures++
+		}
+		backend, err := deps.clipboardBackend()
+		if backend == "" {
+			backend = "none"
+		}
+		if printCheck(out, "clipboard backend", backend, err) {
 			failures++
 		}
 	}
@@ -77,14 +93,15 @@ func runDoctor(ctx context.Context, out io.Writer, deps doctorDeps) error {
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Remote tunnel")
 	if remote {
+		port := client.Port()
 		addresses := []string{
-			"localhost:" + rdmTunnelPort,
-			"127.0.0.1:" + rdmTunnelPort,
-			"[::1]:" + rdmTunnelPort,
+			"localhost:" + port,
+			"127.0.0.1:" + port,
+			"[::1]:" + port,
 		}
 		remoteFailures := 0
 		for _, address := range addresses {
-			if printCheck(out, "server responds over tcp", address, deps.statusTCP(ctx, address)) {
+			if printCheck(out, "server responds over tcp", address, recordStatus(deps.statusTCP(ctx, address))) {
 				failures++
 				remoteFailures++
 			}
@@ -96,6 +113,10 @@ func runDoctor(ctx context.Context, out io.Writer, deps doctorDeps) error {
 		fmt.Fprintln(out, "  - skipped (not running in SSH or Codespaces environment)")
 	}
 
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Version")
+	failures += printVersionChecks(out, status)
+
 	if failures > 0 {
 		return fmt.Errorf("gh-rdm doctor found %d issue(s)", failures)
 	}
@@ -111,6 +132,31 @@ func printCheck(out io.Writer, name, detail string, err error) bool {
 	return true
 }
 
+// printVersionChecks compares the server's version and protocol with this
+// binary's and returns the number of failures. A differing release is only a
+// warning; a differing protocol means commands will fail.
+func printVersionChecks(out io.Writer, status *client.Status) int {
+	if status == nil {
+		fmt.Fprintln(out, "  - skipped (no server responded)")
+		return 0
+	}
+
+	failures := 0
+	if printCheck(out, "protocol matches", fmt.Sprintf("protocol %d", client.ProtocolVersion), status.CheckProtocol()) {
+		failures++
+	}
+
+	switch {
+	case status.Version == "":
+		fmt.Fprintf(out, "  ⚠ server version unknown: this machine runs %s; upgrade gh-rdm on your local machine\n", version.Version)
+	case status.Version != version.Version:
+		fmt.Fprintf(out, "  ⚠ server version differs: server %s (%s/%s), this machine %s\n", status.Version, status.OS, status.Arch, version.Version)
+	default:
+		fmt.Fprintf(out, "  ✓ server version matches: %s (%s/%s)\n", status.Version, status.OS, status.Arch)
+	}
+	return failures
+}
+
 func printRepairCommand(out io.Writer, getenv func(string) string) {
 	fmt.Fprintln(out)
 	if isCodespaceEnvironment(getenv) {
@@ -119,12 +165,14 @@ func printRepairCommand(out io.Writer, getenv func(string) string) {
 			codespace = "<codespace>"
 		}
 		fmt.Fprintln(out, "Repair command (run on your local machine):")
-		fmt.Fprintf(out, "  gh cs ssh -c %s -- -o ExitOnForwardFailure=yes -N -R localhost:%s:$(gh rdm socket)\n", codespace, rdmTunnelPort)
+		fmt.Fprintf(out, "  gh rdm token | gh cs ssh -c %s -- '%s'\n", codespace, remoteTokenScript(client.Port()))
+		fmt.Fprintf(out, "  gh cs ssh -c %s -- -o ExitOnForwardFailure=yes -N -R localhost:%s:$(gh rdm socket)\n", codespace, client.Port())
 		return
 	}
 
 	fmt.Fprintln(out, "Repair command (run on your local machine, replacing <host>):")
-	fmt.Fprintf(out, "  ssh -o ExitOnForwardFailure=yes -N -R localhost:%s:$(gh rdm socket) <host>\n", rdmTunnelPort)
+	fmt.Fprintf(out, "  gh rdm token | ssh <host> '%s'\n", remoteTokenScript(client.Port()))
+	fmt.Fprintf(out, "  ssh -o ExitOnForwardFailure=yes -N -R localhost:%s:$(gh rdm socket) <host>\n", client.Port())
 }
 
 func isRemoteEnvironment(getenv func(string) string) bool {
@@ -138,20 +186,27 @@ func isCodespaceEnvironment(getenv func(string) string) bool {
 	return getenv("CODESPACES") == "true" || getenv("CODESPACE_NAME") != ""
 }
 
-func checkStatus(ctx context.Context, c *client.Client) error {
-	data, err := c.SendCommand(ctx, "status")
+// clipboardBackend names the clipboard tool the server would use, preferring
+// a copy command set in the config file.
+func clipboardBackend() (string, error) {
+	cfg, err := config.Load()
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	var response struct {
-		Status string `json:"status"`
+	if len(cfg.Commands.Copy) > 0 {
+		return fmt.Sprintf("%s (from config)", cfg.Commands.Copy[0]), nil
 	}
-	if err := json.Unmarshal(data, &response); err != nil {
-		return fmt.Errorf("parsing status response: %w", err)
+	return hostservice.New().ClipboardBackend()
+}
+
+// fetchStatus asks c for the server status and checks that it is running.
+func fetchStatus(ctx context.Context, c *client.Client) (*client.Status, error) {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if response.Status != "running" {
-		return errors.New("status response did not report running")
+	if status.Status != "running" {
+		return nil, errors.New("status response did not report running")
 	}
-	return nil
+	return status, nil
 }
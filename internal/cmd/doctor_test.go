@@ -6,6 +6,9 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/version"
 )
 
 func TestRunDoctorLocalHealthy(t *testing.T) {
@@ -21,12 +24,16 @@ func TestRunDoctorLocalHealthy(t *testing.T) {
 	if !strings.Contains(output, "✓ socket path exists: /tmp/gh-rdm.sock") {
 		t.Fatalf("runDoctor() output missing socket success:\n%s", output)
 	}
+	if !strings.Contains(output, "✓ clipboard backend: pbcopy") {
+		t.Fatalf("runDoctor() output missing clipboard backend:\n%s", output)
+	}
 	if !strings.Contains(output, "skipped (not running in SSH or Codespaces environment)") {
 		t.Fatalf("runDoctor() output missing remote skip:\n%s", output)
 	}
 }
 
 func TestRunDoctorCodespaceBrokenTunnelPrintsRepairCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	var out bytes.Buffer
 	deps := fakeDoctorDeps()
 	deps.getenv = func(key string) string {
@@ -39,8 +46,8 @@ func TestRunDoctorCodespaceBrokenTunnelPrintsRepairCommand(t *testing.T) {
 			return ""
 		}
 	}
-	deps.statusTCP = func(context.Context, string) error {
-		return errors.New("connection refused")
+	deps.statusTCP = func(context.Context, string) (*client.Status, error) {
+		return nil, errors.New("connection refused")
 	}
 
 	err := runDoctor(context.Background(), &out, deps)
@@ -60,14 +67,41 @@ func TestRunDoctorCodespaceBrokenTunnelPrintsRepairCommand(t *testing.T) {
 	}
 }
 
+func TestRunDoctorUsesConfiguredPort(t *testing.T) {
+	t.Setenv(client.PortEnv, "7400")
+
+	var out bytes.Buffer
+	deps := fakeDoctorDeps()
+	deps.getenv = func(key string) string {
+		if key == "SSH_TTY" {
+			return "/dev/pts/1"
+		}
+		return ""
+	}
+	var dialed []string
+	deps.statusTCP = func(_ context.Context, address string) (*client.Status, error) {
+		dialed = append(dialed, address)
+		return nil, errors.New("connection refused")
+	}
+
+	runDoctor(context.Background(), &out, deps)
+
+	if len(dialed) == 0 || dialed[0] != "localhost:7400" {
+		t.Fatalf("runDoctor() dialed %v, want localhost:7400 first", dialed)
+	}
+	if want := "-R localhost:7400:$(gh rdm socket) <host>"; !strings.Contains(out.String(), want) {
+		t.Fatalf("runDoctor() output missing %q:\n%s", want, out.String())
+	}
+}
+
 func TestRunDoctorSSHHealthyTunnel(t *testing.T) {
 	var out bytes.Buffer
 	deps := fakeDoctorDeps()
 	deps.statSocket = func(string) error {
 		return errors.New("socket not on remote machine")
 	}
-	deps.statusUnix = func(context.Context, string) error {
-		return errors.New("server not on remote machine")
+	deps.statusUnix = func(context.Context, string) (*client.Status, error) {
+		return nil, errors.New("server not on remote machine")
 	}
 	deps.getenv = func(key string) string {
 		if key == "SSH_CONNECTION" {
@@ -90,6 +124,68 @@ func TestRunDoctorSSHHealthyTunnel(t *testing.T) {
 	}
 }
 
+func TestRunDoctorReportsClipboardBackend(t *testing.T) {
+	var out bytes.Buffer
+	deps := fakeDoctorDeps()
+	deps.clipboardBackend = func() (string, error) {
+		return "", errors.New("no clipboard tool found; install one of: xclip, xsel")
+	}
+
+	err := runDoctor(context.Background(), &out, deps)
+	if err == nil {
+		t.Fatal("runDoctor() error = nil, want error")
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "✗ clipboard backend: none (no clipboard tool found; install one of: xclip, xsel)") {
+		t.Fatalf("runDoctor() output missing clipboard failure:\n%s", output)
+	}
+}
+
+func TestRunDoctorFlagsProtocolMismatch(t *testing.T) {
+	var out bytes.Buffer
+	deps := fakeDoctorDeps()
+	deps.statusUnix = func(context.Context, string) (*client.Status, error) {
+		return &client.Status{Status: "running", Version: "v0.4.0"}, nil
+	}
+
+	err := runDoctor(context.Background(), &out, deps)
+	if err == nil {
+		t.Fatal("runDoctor() error = nil, want error")
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "✗ protocol matches") {
+		t.Fatalf("runDoctor() output missing protocol failure:\n%s", output)
+	}
+	if !strings.Contains(output, "⚠ server version differs: server v0.4.0") {
+		t.Fatalf("runDoctor() output missing version warning:\n%s", output)
+	}
+}
+
+func TestRunDoctorVersionMatches(t *testing.T) {
+	var out bytes.Buffer
+
+	if err := runDoctor(context.Background(), &out, fakeDoctorDeps()); err != nil {
+		t.Fatalf("runDoctor() error = %v, want nil", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "✓ server version matches: "+version.Version) {
+		t.Fatalf("runDoctor() output missing version match:\n%s", output)
+	}
+}
+
+func healthyStatus() *client.Status {
+	return &client.Status{
+		Status:          "running",
+		Version:         version.Version,
+		ProtocolVersion: client.ProtocolVersion,
+		OS:              "darwin",
+		Arch:            "arm64",
+	}
+}
+
 func fakeDoctorDeps() doctorDeps {
 	return doctorDeps{
 		socketPath: func() string {
@@ -98,11 +194,14 @@ func fakeDoctorDeps() doctorDeps {
 		statSocket: func(string) error {
 			return nil
 		},
-		statusUnix: func(context.Context, string) error {
-			return nil
+		statusUnix: func(context.Context, string) (*client.Status, error) {
+			return healthyStatus(), nil
 		},
-		statusTCP: func(context.Context, string) error {
-			return nil
+		statusTCP: func(context.Context, string) (*client.Status, error) {
+			return healthyStatus(), nil
+		},
+		clipboardBackend: func() (string, error) {
+			return "pbcopy", nil
 		},
 		getenv: func(string) string {
 			return ""
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/hostservice"
+	"github.com/spf13/cobra"
+)
+
+func newFetchCmd() *cobra.Command {
+	var outputDir string
+	var list bool
+
+	cmd := &cobra.Command{
+		Use:   "fetch <local-path>...",
+		Short: "Fetch files from allowlisted directories on the local machine",
+		Long: `Fetch files from the local machine via the gh-rdm tunnel.
+
+The server only serves files from its allowlisted directories (~/Downloads and
+~/Desktop unless started with --allow-dir). Relative paths are taken relative to
+the first allowlisted directory. Paths in the remote home directory, such as
+an unquoted ~/Downloads the remote shell expanded, are sent as ~/... and so
+refer to the same place in the local home directory.
+
+Fetched files never overwrite existing ones; a numbered name is used instead.
+
+Use --ls to list a local directory, newest first.`,
+		Example: `  gh rdm fetch --ls
+  gh rdm fetch report.pdf
+  gh rdm fetch --ls ~/Desktop
+  gh rdm fetch ~/Desktop/notes.txt -o /tmp`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := client.New()
+			out := cmd.OutOrStdout()
+
+			if list {
+				if len(args) > 1 {
+					return fmt.Errorf("--ls takes at most one directory")
+				}
+				var dir string
+				if len(args) == 1 {
+					dir = localHomePath(args[0])
+				}
+				return listLocalDir(cmd, c, dir)
+			}
+
+			if len(args) == 0 {
+				return fmt.Errorf("pass at least one path, or --ls to list a directory")
+			}
+			if err := c.RequireFeature(cmd.Context(), client.FeatureStream, "fetching files"); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			for _, path := range args {
+				outPath, size, err := fetchFile(cmd, c, path, outputDir)
+				if err != nil {
+					return fmt.Errorf("fetch %s: %w", path, err)
+				}
+				fmt.Fprintf(out, "📥 Fetched %s → %s (%s)\n", path, outPath, formatSize(size))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", ".", "Directory to save fetched files")
+	cmd.Flags().BoolVar(&list, "ls", false, "List a local directory instead of fetching")
+
+	return cmd
+}
+
+// localHomePath rewrites a path in this machine's home directory to start
+// with ~, which the server resolves against the local home directory. The
+// remote shell expands an unquoted ~ before gh rdm sees it.
+func localHomePath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" || home == "/" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(path, home+"/"); ok {
+		return "~/" + rest
+	}
+	return path
+}
+
+func fetchFile(cmd *cobra.Command, c *client.Client, path, outputDir string) (string, int64, error) {
+	stream, err := c.FetchStream(cmd.Context(), "fetch", localHomePath(path))
+	if err != nil {
+		return "", 0, err
+	}
+	defer stream.Body.Close()
+
+	name := filepath.Base(stream.Filename)
+	if name == "." || name == "/" || name == ".." {
+		name = filepath.Base(path)
+	}
+
+	f, outPath, err := hostservice.CreateUnique(outputDir, name)
+	if err != nil {
+		return "", 0, err
+	}
+	size, err := io.Copy(f, stream.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return "", 0, err
+	}
+	return outPath, size, nil
+}
+
+func listLocalDir(cmd *cobra.Command, c *client.Client, dir string) error {
+	data, err := c.SendCommand(cmd.Context(), "list", dir)
+	if err != nil {
+		return err
+	}
+
+	var files []hostservice.FileInfo
+	if err := json.Unmarshal(data, &files); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	printFileTable(cmd.OutOrStdout(), files)
+	return nil
+}
+
+func printFileTable(out io.Writer, files []hostservice.FileInfo) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, f := range files {
+		name := f.Name
+		size := formatSize(f.Size)
+		if f.IsDir {
+			name += "/"
+			size = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.ModTime.Local().Format("2006-01-02 15:04"), size, name)
+	}
+	tw.Flush()
+}
+
+// formatSize renders n bytes for humans, e.g. "1.5 MB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import "testing"
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int64
+		want string
+	}{
+		{"bytes", 512, "512 B"},
+		{"kilobytes", 1536, "1.5 KB"},
+		{"megabytes", 3 * 1024 * 1024, "3.0 MB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSize(tt.n); got != tt.want {
+				t.Errorf("formatSize(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalHomePath(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	tests := map[string]string{
+		"/home/me":                "~",
+		"/home/me/Downloads":      "~/Downloads",
+		"/home/me/Desktop/a.txt":  "~/Desktop/a.txt",
+		"/home/meg/Downloads":     "/home/meg/Downloads",
+		"~/Downloads":             "~/Downloads",
+		"report.pdf":              "report.pdf",
+		"/Users/me/Downloads/a.z": "/Users/me/Downloads/a.z",
+	}
+	for path, want := range tests {
+		if got := localHomePath(path); got != want {
+			t.Errorf("localHomePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// historyPreviewWidth is how much of each entry gh rdm history shows.
+const historyPreviewWidth = 60
+
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recent clipboard copies",
+		Long: `List the recent copies the local server remembers, newest first.
+
+The server keeps the history in memory only, unless it was started with
+--history-file.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := client.New()
+			data, err := c.SendCommand(cmd.Context(), "history")
+			if err != nil {
+				return err
+			}
+
+			var entries []client.HistoryEntry
+			if err := json.Unmarshal(data, &entries); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+			if len(entries) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No clipboard history yet.")
+				return nil
+			}
+			printHistory(cmd.OutOrStdout(), entries, time.Now())
+			return nil
+		},
+	}
+
+	cmd.AddCommand(newHistoryPasteCmd())
+
+	return cmd
+}
+
+func newHistoryPasteCmd() *cobra.Command {
+	var copyBack bool
+
+	cmd := &cobra.Command{
+		Use:   "paste <n>",
+		Short: "Print a history entry, or put it back on the clipboard",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid history entry %q", args[0])
+			}
+
+			action := "get"
+			if copyBack {
+				action = "copy"
+			}
+
+			c := client.New()
+			data, err := c.SendCommand(cmd.Context(), "history", action, args[0])
+			if err != nil {
+				return err
+			}
+			if copyBack {
+				fmt.Fprintf(cmd.ErrOrStderr(), "📋 Copied entry %s to clipboard\n", args[0])
+				return nil
+			}
+			fmt.Fprint(cmd.OutOrStdout(), string(data))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&copyBack, "copy", "c", false, "Put the entry back on the clipboard instead of printing it")
+
+	return cmd
+}
+
+func printHistory(out io.Writer, entries []client.HistoryEntry, now time.Time) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, entry := range entries {
+		session := entry.Session
+		if session == "" {
+			session = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", i+1, formatAge(now.Sub(entry.Time)), session, previewText(entry.Text, historyPreviewWidth))
+	}
+	tw.Flush()
+}
+
+// previewText flattens text onto one line and truncates it to width runes.
+func previewText(text string, width int) string {
+	flat := strings.Join(strings.Fields(text), " ")
+	runes := []rune(flat)
+	if len(runes) <= width {
+		return flat
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// formatAge renders d as a short relative age, e.g. "5m ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+)
+
+func TestPrintHistory(t *testing.T) {
+	now := time.Date(2026, 3, 6, 12, 0, 0, 0, time.UTC)
+	entries := []client.HistoryEntry{
+		{Text: "git push origin main", Time: now.Add(-30 * time.Second), Session: "shiny-space"},
+		{Text: "line one\nline two", Time: now.Add(-2 * time.Hour)},
+	}
+
+	var out bytes.Buffer
+	printHistory(&out, entries, now)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("printHistory() lines = %d, want 2:\n%s", len(lines), out.String())
+	}
+	for _, want := range []string{"1", "just now", "shiny-space", "git push origin main"} {
+		if !strings.Contains(lines[0], want) {
+			t.Fatalf("printHistory() first line missing %q: %q", want, lines[0])
+		}
+	}
+	if !strings.Contains(lines[1], "2h ago") || !strings.Contains(lines[1], "line one line two") {
+		t.Fatalf("printHistory() second line = %q, want flattened text and age", lines[1])
+	}
+}
+
+func TestPreviewText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{"short", "hello", 10, "hello"},
+		{"flattened", "a\n  b\tc", 10, "a b c"},
+		{"truncated", "abcdefghij", 5, "abcd…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previewText(tt.text, tt.width); got != tt.want {
+				t.Errorf("previewText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func newNotifyCmd() *cobra.Command {
+	var title string
+
+	cmd := &cobra.Command{
+		Use:   "notify [message...]",
+		Short: "Show a desktop notification on the local machine",
+		Long: `Show a native desktop notification on the local machine.
+The message is read from stdin when no arguments are given.`,
+		Example: `  make test && gh rdm notify --title build "tests passed"
+  ./long-job.sh 2>&1 | tail -1 | gh rdm notify --title long-job`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			message := strings.Join(args, " ")
+			if len(args) == 0 {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return err
+				}
+				message = strings.TrimSpace(string(data))
+			}
+			if message == "" {
+				return fmt.Errorf("notification message is empty")
+			}
+
+			c := client.New()
+			_, err := c.SendCommand(cmd.Context(), "notify", title, message)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&title, "title", "t", "gh-rdm", "Notification title")
+
+	return cmd
+}
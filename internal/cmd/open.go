@@ -12,6 +12,8 @@ func newOpenCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := client.New()
+			// The server may ask the local user before opening.
+			c.SetTimeout(client.PromptTimeout + client.IdleTimeout)
 			_, err := c.SendCommand(cmd.Context(), "open", args[0])
 			return err
 		},
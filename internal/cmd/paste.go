@@ -2,17 +2,39 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
 	"github.com/spf13/cobra"
 )
 
 func newPasteCmd() *cobra.Command {
-	return &cobra.Command{
+	var mimeType string
+
+	cmd := &cobra.Command{
 		Use:   "paste",
 		Short: "Paste clipboard content to stdout",
+		Long: `Paste the local clipboard to stdout.
+
+Use --type to request a specific representation, e.g. --type text/html or
+--type image/png.`,
+		Example: `  gh rdm paste
+  gh rdm paste --type image/png > clipboard.png`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := client.New()
+			if mimeType != "" && mimeType != "text/plain" {
+				if err := c.RequireFeature(cmd.Context(), client.FeatureMIMEClipboard, "pasting with --type"); err != nil {
+					return err
+				}
+				stream, err := c.FetchStream(cmd.Context(), "paste", mimeType)
+				if err != nil {
+					return err
+				}
+				defer stream.Body.Close()
+				_, err = io.Copy(cmd.OutOrStdout(), stream.Body)
+				return err
+			}
+
 			result, err := c.SendCommand(cmd.Context(), "paste")
 			if err != nil {
 				return err
@@ -22,4 +44,8 @@ func newPasteCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&mimeType, "type", "t", "", "MIME type to request, e.g. text/html or image/png")
+
+	return cmd
 }
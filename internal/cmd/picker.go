@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+)
+
+// pickerRows is how many codespaces the picker shows at once.
+const pickerRows = 10
+
+var (
+	// errNoTerminal means the picker can't run, so the caller should fall
+	// back to its non-interactive behaviour.
+	errNoTerminal = errors.New("not a terminal")
+	// errPickerCancelled means the user backed out of the picker.
+	errPickerCancelled = errors.New("no codespace selected")
+)
+
+// pickCodespaceInteractive lets the user choose one of codespaces on the
+// terminal, starting on the one last picked for the current repository.
+func pickCodespaceInteractive(ctx context.Context, codespaces []codespace) (string, error) {
+	restore, err := rawTerminal()
+	if err != nil {
+		return "", errNoTerminal
+	}
+	defer restore()
+
+	repo := currentRepository(ctx)
+	last := readLastCodespaces()
+	name, err := runPicker(os.Stdin, os.Stderr, sortCodespaces(codespaces), last[repo])
+	if err != nil {
+		return "", err
+	}
+
+	last[repo] = name
+	if err := writeLastCodespaces(last); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not remember codespace choice: %v\n", err)
+	}
+	return name, nil
+}
+
+// sortCodespaces returns codespaces with the Available ones first, keeping
+// the order within each group.
+func sortCodespaces(codespaces []codespace) []codespace {
+	sorted := slices.Clone(codespaces)
+	slices.SortStableFunc(sorted, func(a, b codespace) int {
+		return availableRank(a) - availableRank(b)
+	})
+	return sorted
+}
+
+func availableRank(c codespace) int {
+	if c.State == "Available" {
+		return 0
+	}
+	return 1
+}
+
+// picker is the state of the codespace picker: the text typed so far and
+// the highlighted row among the matches.
+type picker struct {
+	items  []codespace
+	query  string
+	cursor int
+}
+
+// matches returns the items whose name and repository contain the query's
+// characters in order, ignoring case.
+func (p *picker) matches() []codespace {
+	var matched []codespace
+	for _, item := range p.items {
+		if fuzzyMatch(strings.ToLower(item.Name+" "+item.Repository), strings.ToLower(p.query)) {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+func fuzzyMatch(text, query string) bool {
+	for _, r := range query {
+		i := strings.IndexRune(text, r)
+		if i < 0 {
+			return false
+		}
+		text = text[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// runPicker reads keys from in and draws the picker on out until the user
+// picks a codespace or cancels. preselect names the codespace to start on.
+func runPicker(in io.Reader, out io.Writer, items []codespace, preselect string) (string, error) {
+	p := &picker{items: items}
+	if i := slices.IndexFunc(items, func(c codespace) bool { return c.Name == preselect }); i >= 0 {
+		p.cursor = i
+	}
+
+	drawn := 0
+	clear := func() {
+		if drawn > 0 {
+			fmt.Fprintf(out, "\x1b[%dA\x1b[J", drawn)
+		}
+	}
+	defer clear()
+
+	buf := make([]byte, 64)
+	for {
+		clear()
+		drawn = p.draw(out)
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return "", errPickerCancelled
+		}
+		if name, done, err := p.handle(buf[:n]); done {
+			return name, err
+		}
+	}
+}
+
+// handle applies the keys in one read. It reports done with the chosen name,
+// or with errPickerCancelled.
+func (p *picker) handle(keys []byte) (string, bool, error) {
+	if len(keys) == 1 && keys[0] == 0x1b {
+		// A lone escape, not the start of an arrow key.
+		return "", true, errPickerCancelled
+	}
+
+	for i := 0; i < len(keys); i++ {
+		switch c := keys[i]; {
+		case c == 0x1b:
+			// Escape sequences: arrows are ESC [ A or ESC O A. Skip others.
+			if i+2 < len(keys) && (keys[i+1] == '[' || keys[i+1] == 'O') {
+				switch keys[i+2] {
+				case 'A':
+					p.move(-1)
+				case 'B':
+					p.move(1)
+				}
+				i += 2
+				for i < len(keys) && (keys[i] < 0x40 || keys[i] > 0x7e) {
+					i++
+				}
+			}
+		case c == 0x03 || c == 0x04:
+			return "", true, errPickerCancelled
+		case c == '\r' || c == '\n':
+			matched := p.matches()
+			if len(matched) > 0 {
+				return matched[p.cursor].Name, true, nil
+			}
+		case c == 0x10: // Ctrl-P
+			p.move(-1)
+		case c == 0x0e: // Ctrl-N
+			p.move(1)
+		case c == 0x7f || c == 0x08:
+			if p.query != "" {
+				_, size := utf8.DecodeLastRuneInString(p.query)
+				p.query = p.query[:len(p.query)-size]
+				p.cursor = 0
+			}
+		case c == 0x15: // Ctrl-U
+			p.query = ""
+			p.cursor = 0
+		case c >= 0x20:
+			// Typed characters may be several bytes long; drop invalid ones.
+			r, size := utf8.DecodeRune(keys[i:])
+			i += size - 1
+			if r != utf8.RuneError {
+				p.query += string(r)
+				p.cursor = 0
+			}
+		}
+	}
+	return "", false, nil
+}
+
+func (p *picker) move(delta int) {
+	if n := len(p.matches()); n > 0 {
+		p.cursor = (p.cursor + delta + n) % n
+	}
+}
+
+// draw renders the picker and returns how many lines it used.
+func (p *picker) draw(out io.Writer) int {
+	matched := p.matches()
+	p.cursor = min(p.cursor, max(0, len(matched)-1))
+
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "? Select a codespace (type to filter, ↑/↓ to move, enter to pick, esc to cancel)")
+	fmt.Fprintf(&b, "> %s\n", p.query)
+	lines := 2
+
+	if len(matched) == 0 {
+		fmt.Fprintln(&b, "  no matching codespaces")
+		lines++
+	}
+	start := max(0, min(p.cursor-pickerRows/2, len(matched)-pickerRows))
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for i := start; i < min(len(matched), start+pickerRows); i++ {
+		marker := " "
+		if i == p.cursor {
+			marker = "❯"
+		}
+		c := matched[i]
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\n", marker, c.Name, c.State, c.Repository)
+		lines++
+	}
+	tw.Flush()
+
+	out.Write(b.Bytes())
+	return lines
+}
+
+// rawTerminal turns off line buffering, echo and signal keys on stdin so the
+// picker sees each key, and returns a function restoring the old settings.
+func rawTerminal() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "-isig", "min", "1", "time", "0"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// currentRepository returns the owner/name of the working directory's
+// origin remote, or "" outside a repository.
+func currentRepository(ctx context.Context) string {
+	output, err := exec.CommandContext(ctx, "git", "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		return ""
+	}
+	return repositoryFromRemote(strings.TrimSpace(string(output)))
+}
+
+// repositoryFromRemote extracts owner/name from a remote URL such as
+// https://github.com/owner/name.git or git@github.com:owner/name.git.
+func repositoryFromRemote(remote string) string {
+	path := remote
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" {
+		path = u.Path
+	} else if _, after, ok := strings.Cut(remote, ":"); ok {
+		path = after
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return ""
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+func lastCodespacePath() (string, error) {
+	dir, err := client.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "last-codespace.json"), nil
+}
+
+// readLastCodespaces returns the codespace last picked for each repository.
+// A missing or unreadable file is an empty map.
+func readLastCodespaces() map[string]string {
+	last := map[string]string{}
+	path, err := lastCodespacePath()
+	if err != nil {
+		return last
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return last
+	}
+	json.Unmarshal(data, &last)
+	return last
+}
+
+func writeLastCodespaces(last map[string]string) error {
+	path, err := lastCodespacePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(last, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var pickerItems = []codespace{
+	{Name: "api-space", State: "Available", Repository: "octo/api"},
+	{Name: "web-space", State: "Available", Repository: "octo/web"},
+	{Name: "docs-space", State: "Shutdown", Repository: "octo/docs"},
+}
+
+func TestRunPicker(t *testing.T) {
+	tests := []struct {
+		name      string
+		keys      string
+		preselect string
+		want      string
+		wantErr   error
+	}{
+		{"enter picks the first", "\r", "", "api-space", nil},
+		{"arrow down", "\x1b[B\r", "", "web-space", nil},
+		{"arrow up wraps", "\x1b[A\r", "", "docs-space", nil},
+		{"fuzzy filter on repository", "odoc\r", "", "docs-space", nil},
+		{"backspace widens the filter", "docsx\x7f\r", "", "docs-space", nil},
+		{"starts on the preselected codespace", "\r", "web-space", "web-space", nil},
+		{"escape cancels", "\x1b", "", "", errPickerCancelled},
+		{"ctrl-c cancels", "\x03", "", "", errPickerCancelled},
+		{"end of input cancels", "", "", "", errPickerCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := runPicker(&chunkReader{keys: splitKeys(tt.keys)}, &out, pickerItems, tt.preselect)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runPicker() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("runPicker() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPickerFiltersOnMultiByteCharacters(t *testing.T) {
+	items := []codespace{
+		{Name: "cafe-space", State: "Available", Repository: "octo/cafe"},
+		{Name: "café-space", State: "Available", Repository: "octo/café"},
+	}
+
+	var out bytes.Buffer
+	got, err := runPicker(&chunkReader{keys: splitKeys("é\r")}, &out, items, "")
+	if err != nil {
+		t.Fatalf("runPicker() error = %v", err)
+	}
+	if got != "café-space" {
+		t.Fatalf("runPicker() = %q, want %q", got, "café-space")
+	}
+	if !strings.Contains(out.String(), "> é\n") {
+		t.Fatalf("picker did not echo the typed character:\n%s", out.String())
+	}
+}
+
+func TestRunPickerShowsStateAndRepository(t *testing.T) {
+	var out bytes.Buffer
+	runPicker(&chunkReader{keys: []string{"\r"}}, &out, pickerItems, "")
+
+	for _, want := range []string{"❯ api-space", "Available", "octo/api", "docs-space", "Shutdown"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("picker output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSortCodespacesListsAvailableFirst(t *testing.T) {
+	sorted := sortCodespaces([]codespace{
+		{Name: "a", State: "Shutdown"},
+		{Name: "b", State: "Available"},
+		{Name: "c", State: "Starting"},
+		{Name: "d", State: "Available"},
+	})
+
+	var names []string
+	for _, c := range sorted {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "b,d,a,c" {
+		t.Fatalf("sortCodespaces() = %s, want b,d,a,c", got)
+	}
+}
+
+func TestRepositoryFromRemote(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/octo/web.git": "octo/web",
+		"https://github.com/octo/web":     "octo/web",
+		"git@github.com:octo/web.git":     "octo/web",
+		"ssh://git@github.com/octo/web":   "octo/web",
+		"":                                "",
+		"not-a-remote":                    "",
+	}
+	for remote, want := range tests {
+		if got := repositoryFromRemote(remote); got != want {
+			t.Errorf("repositoryFromRemote(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestLastCodespacesRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := writeLastCodespaces(map[string]string{"octo/web": "web-space"}); err != nil {
+		t.Fatalf("writeLastCodespaces() error = %v", err)
+	}
+	if got := readLastCodespaces()["octo/web"]; got != "web-space" {
+		t.Fatalf("readLastCodespaces() = %q, want web-space", got)
+	}
+}
+
+// chunkReader returns one key sequence per Read, like a terminal does.
+type chunkReader struct {
+	keys []string
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.keys) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.keys[0])
+	r.keys = r.keys[1:]
+	return n, nil
+}
+
+// splitKeys splits typed input into terminal reads: escape sequences stay
+// whole and every other byte is its own read.
+func splitKeys(s string) []string {
+	var keys []string
+	for len(s) > 0 {
+		_, n := utf8.DecodeRuneInString(s)
+		if strings.HasPrefix(s, "\x1b[") && len(s) >= 3 {
+			n = 3
+		}
+		keys = append(keys, s[:n])
+		s = s[n:]
+	}
+	return keys
+}
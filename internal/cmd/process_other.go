@@ -0,0 +1,19 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// processAlive reports whether a process with pid exists. Outside Unix,
+// os.FindProcess opens the process and so fails once it has exited.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package cmd
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process with pid exists. Signal 0 checks
+// without delivering anything; EPERM means it exists but isn't ours.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -2,17 +2,33 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strconv"
 
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/config"
+	"github.com/maxbeizer/gh-rdm/internal/version"
 	"github.com/spf13/cobra"
 )
 
 func Execute(ctx context.Context, userMessages *log.Logger) error {
+	var port, socket string
+
 	rootCmd := &cobra.Command{
-		Use:   "gh-rdm",
-		Short: "Remote Development Manager - clipboard and open forwarding over SSH",
+		Use:     "gh-rdm",
+		Short:   "Remote Development Manager - clipboard and open forwarding over SSH",
+		Version: version.Version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyConnectionSettings(port, socket, config.Load)
+		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&port, "port", "", fmt.Sprintf("Remote port the tunnel forwards (default $%s, the config file, or a free port picked by the remote sshd for tunnels and %d otherwise)", client.PortEnv, client.DefaultPort))
+	rootCmd.PersistentFlags().StringVar(&socket, "socket", "", fmt.Sprintf("Unix socket the local server listens on (default $%s, the config file or a temporary path)", client.SocketEnv))
+
 	rootCmd.AddCommand(
 		newServerCmd(userMessages),
 		newStopCmd(),
@@ -20,12 +36,56 @@ func Execute(ctx context.Context, userMessages *log.Logger) error {
 		newPasteCmd(),
 		newOpenCmd(),
 		newSocketCmd(),
+		newTokenCmd(),
 		newSetupCmd(),
 		newDoctorCmd(),
 		newTunnelCmd(),
+		newSessionsCmd(),
+		newSSHCmd(),
+		newCSCmd(),
 		newScreenshotCmd(),
 		newClipboardImageCmd(),
+		newSendCmd(),
+		newFetchCmd(),
+		newNotifyCmd(),
+		newHistoryCmd(),
 	)
 
 	return rootCmd.ExecuteContext(ctx)
 }
+
+// applyConnectionSettings exports the tunnel port and socket path to the
+// environment, where the client package and any gh rdm process started from
+// here read them. Flags win over the environment, which wins over the config
+// file.
+func applyConnectionSettings(port, socket string, loadConfig func() (*config.Config, error)) error {
+	if port == "" && os.Getenv(client.PortEnv) == "" || socket == "" && os.Getenv(client.SocketEnv) == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if port == "" && os.Getenv(client.PortEnv) == "" && cfg.Port != 0 {
+			port = strconv.Itoa(cfg.Port)
+		}
+		if socket == "" && os.Getenv(client.SocketEnv) == "" {
+			socket = cfg.Socket
+		}
+	}
+
+	if port != "" {
+		os.Setenv(client.PortEnv, port)
+	}
+	if socket != "" {
+		os.Setenv(client.SocketEnv, socket)
+	}
+
+	if port := os.Getenv(client.PortEnv); port != "" {
+		if err := client.ValidatePort(port); err != nil {
+			return err
+		}
+	}
+	if socket := os.Getenv(client.SocketEnv); socket != "" && !filepath.IsAbs(socket) {
+		return fmt.Errorf("socket path %q must be absolute", socket)
+	}
+	return nil
+}
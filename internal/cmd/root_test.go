@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/config"
+)
+
+func TestApplyConnectionSettingsPrecedence(t *testing.T) {
+	loadConfig := func() (*config.Config, error) {
+		return &config.Config{Port: 7000, Socket: "/config/rdm.sock"}, nil
+	}
+
+	tests := []struct {
+		name       string
+		flagPort   string
+		envPort    string
+		flagSocket string
+		envSocket  string
+		wantPort   string
+		wantSocket string
+	}{
+		{"config", "", "", "", "", "7000", "/config/rdm.sock"},
+		{"environment over config", "", "8000", "", "/env/rdm.sock", "8000", "/env/rdm.sock"},
+		{"flags over environment", "9000", "8000", "/flag/rdm.sock", "/env/rdm.sock", "9000", "/flag/rdm.sock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(client.PortEnv, tt.envPort)
+			t.Setenv(client.SocketEnv, tt.envSocket)
+
+			if err := applyConnectionSettings(tt.flagPort, tt.flagSocket, loadConfig); err != nil {
+				t.Fatalf("applyConnectionSettings() error = %v", err)
+			}
+			if got := os.Getenv(client.PortEnv); got != tt.wantPort {
+				t.Errorf("$%s = %q, want %q", client.PortEnv, got, tt.wantPort)
+			}
+			if got := os.Getenv(client.SocketEnv); got != tt.wantSocket {
+				t.Errorf("$%s = %q, want %q", client.SocketEnv, got, tt.wantSocket)
+			}
+		})
+	}
+}
+
+func TestApplyConnectionSettingsRejectsBadValues(t *testing.T) {
+	noConfig := func() (*config.Config, error) { return &config.Config{}, nil }
+
+	t.Setenv(client.PortEnv, "")
+	t.Setenv(client.SocketEnv, "")
+	if err := applyConnectionSettings("http", "", noConfig); err == nil {
+		t.Error("applyConnectionSettings() accepted port \"http\"")
+	}
+
+	t.Setenv(client.PortEnv, "")
+	if err := applyConnectionSettings("", "rdm.sock", noConfig); err == nil {
+		t.Error("applyConnectionSettings() accepted a relative socket path")
+	}
+}
@@ -1,41 +1,104 @@
 package cmd
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/hostservice"
+	"github.com/maxbeizer/gh-rdm/internal/imageutil"
 	"github.com/spf13/cobra"
 )
 
-type imageResponse struct {
-	Filename string `json:"filename"`
-	Data     string `json:"data"`
-}
-
 func newScreenshotCmd() *cobra.Command {
 	var outputDir string
 	var copyRef bool
+	var list bool
+	var last int
+	var since time.Duration
+	var names []string
+	var watch bool
+	var images imageFlags
 
 	cmd := &cobra.Command{
 		Use:   "screenshot",
-		Short: "Fetch the latest screenshot from the local machine",
-		Long: `Fetch the latest screenshot from the local machine's Desktop via
-the gh-rdm tunnel and save it to a file on the remote machine.
+		Short: "Fetch screenshots from the local machine",
+		Long: `Fetch the latest screenshot from the local machine via the gh-rdm
+tunnel and save it to a file on the remote machine. Screenshots are read from
+the Desktop on macOS and from Pictures/Screenshots (or Pictures) on Linux.
 
-Outputs the file path as an @ reference, ready to paste into Copilot CLI.
-By default, the @ reference is also copied to your clipboard.`,
+Use --list to see recent screenshots, and --last, --since or --name to fetch
+several at once. With --watch the command keeps running and fetches each new
+screenshot as soon as it is taken.
+
+Use --max-width, --format and --quality to have the local machine shrink and
+re-encode images before sending them.
+
+Outputs each file path as an @ reference, ready to paste into Copilot CLI.
+By default, the @ references are also copied to your clipboard.`,
+		Example: `  gh rdm screenshot
+  gh rdm screenshot --list
+  gh rdm screenshot --last 3
+  gh rdm screenshot --since 10m
+  gh rdm screenshot --watch
+  gh rdm screenshot --name 'Screenshot 2026-10-17 at 09.41.12.png'
+  gh rdm screenshot --max-width 1600 --format jpeg`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return fetchImage(cmd, "screenshot", outputDir, copyRef)
+			c, err := images.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			selecting := list || cmd.Flags().Changed("last") || cmd.Flags().Changed("since") || len(names) > 0
+			if selecting {
+				if err := c.RequireFeature(cmd.Context(), client.FeatureScreenshotSelect, "listing and selecting screenshots"); err != nil {
+					return err
+				}
+			}
+			if watch {
+				if err := c.RequireFeature(cmd.Context(), client.FeatureScreenshotWatch, "watching for screenshots"); err != nil {
+					return err
+				}
+			}
+
+			switch {
+			case list:
+				return listScreenshots(cmd, c)
+			case watch:
+				return watchScreenshots(cmd, c, outputDir, copyRef)
+			case cmd.Flags().Changed("last"):
+				if last < 1 {
+					return fmt.Errorf("--last must be at least 1")
+				}
+				return fetchScreenshots(cmd, c, outputDir, copyRef, "last", strconv.Itoa(last))
+			case cmd.Flags().Changed("since"):
+				if since <= 0 {
+					return fmt.Errorf("--since must be a positive duration such as 10m")
+				}
+				return fetchScreenshots(cmd, c, outputDir, copyRef, "since", since.String())
+			case len(names) > 0:
+				return fetchScreenshots(cmd, c, outputDir, copyRef, append([]string{"name"}, names...)...)
+			}
+			return fetchImage(cmd, c, "screenshot", outputDir, copyRef)
 		},
 	}
 
 	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "/tmp", "Directory to save the screenshot")
 	cmd.Flags().BoolVarP(&copyRef, "copy", "c", true, "Copy the @ reference to clipboard")
+	cmd.Flags().BoolVarP(&list, "list", "l", false, "List recent screenshots on the local machine")
+	cmd.Flags().IntVarP(&last, "last", "n", 0, "Fetch the `N` most recent screenshots")
+	cmd.Flags().DurationVar(&since, "since", 0, "Fetch screenshots taken within this `duration`, e.g. 10m")
+	cmd.Flags().StringArrayVar(&names, "name", nil, "Fetch the screenshot with this file name; repeatable")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Keep running and fetch each new screenshot as it is taken")
+	cmd.MarkFlagsMutuallyExclusive("list", "last", "since", "name", "watch")
+	images.register(cmd)
 
 	return cmd
 }
@@ -43,6 +106,7 @@ By default, the @ reference is also copied to your clipboard.`,
 func newClipboardImageCmd() *cobra.Command {
 	var outputDir string
 	var copyRef bool
+	var images imageFlags
 
 	cmd := &cobra.Command{
 		Use:   "clipboard-image",
@@ -50,62 +114,242 @@ func newClipboardImageCmd() *cobra.Command {
 		Long: `Grab the current image from the local machine's clipboard via
 the gh-rdm tunnel and save it to a file on the remote machine.
 
-Use ⌘⇧⌃4 to screenshot directly to clipboard, then run this command.
+Use ⌘⇧⌃4 on macOS, or your desktop's "copy screenshot" shortcut on Linux,
+to screenshot directly to clipboard, then run this command.
 Outputs the file path as an @ reference, ready to paste into Copilot CLI.
 By default, the @ reference is also copied to your clipboard.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return fetchImage(cmd, "clipboard-image", outputDir, copyRef)
+			c, err := images.newClient(cmd)
+			if err != nil {
+				return err
+			}
+			return fetchImage(cmd, c, "clipboard-image", outputDir, copyRef)
 		},
 	}
 
 	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "/tmp", "Directory to save the image")
 	cmd.Flags().BoolVarP(&copyRef, "copy", "c", true, "Copy the @ reference to clipboard")
+	images.register(cmd)
 
 	return cmd
 }
 
-func fetchImage(cmd *cobra.Command, commandName string, outputDir string, copyRef bool) error {
-	c := client.New()
-	result, err := c.SendCommand(cmd.Context(), commandName)
+func fetchImage(cmd *cobra.Command, c *client.Client, commandName string, outputDir string, copyRef bool) error {
+	stream, err := c.FetchStream(cmd.Context(), commandName)
 	if err != nil {
 		return fmt.Errorf("failed to fetch image: %w", err)
 	}
+	defer stream.Body.Close()
 
-	var resp imageResponse
-	if err := json.Unmarshal(result, &resp); err != nil {
-		return fmt.Errorf("failed to parse response: %w\nraw: %s", err, string(result))
+	timestamp := time.Now().Format("20060102-150405")
+	ext := filepath.Ext(stream.Filename)
+	if ext == "" {
+		ext = ".png"
+	}
+	filename := fmt.Sprintf("screenshot-%s%s", timestamp, ext)
+	outPath := filepath.Join(outputDir, filename)
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	data, err := base64.StdEncoding.DecodeString(resp.Data)
+	size, err := writeStream(outPath, stream.Body)
 	if err != nil {
-		return fmt.Errorf("failed to decode image data: %w", err)
+		return fmt.Errorf("failed to write image: %w", err)
 	}
 
-	timestamp := time.Now().Format("20060102-150405")
-	filename := fmt.Sprintf("screenshot-%s.png", timestamp)
-	outPath := filepath.Join(outputDir, filename)
+	printSaved(cmd, outPath, size, stream.OriginalSize)
+	printRefs(cmd, []string{outPath}, copyRef)
+
+	return nil
+}
+
+// listScreenshots prints the local machine's recent screenshots.
+func listScreenshots(cmd *cobra.Command, c *client.Client) error {
+	data, err := c.SendCommand(cmd.Context(), "screenshots", "list")
+	if err != nil {
+		return err
+	}
+
+	var files []hostservice.FileInfo
+	if err := json.Unmarshal(data, &files); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No screenshots found")
+		return nil
+	}
+
+	printFileTable(cmd.OutOrStdout(), files)
+	return nil
+}
 
+// fetchScreenshots fetches the screenshots selected by args in one request
+// and saves each under outputDir.
+func fetchScreenshots(cmd *cobra.Command, c *client.Client, outputDir string, copyRef bool, args ...string) error {
 	if err := os.MkdirAll(outputDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	if err := os.WriteFile(outPath, data, 0o644); err != nil {
-		return fmt.Errorf("failed to write image: %w", err)
+	var paths []string
+	used := map[string]bool{}
+	err := c.FetchFiles(cmd.Context(), "screenshots", func(stream *client.Stream) error {
+		outPath := filepath.Join(outputDir, screenshotFilename(stream, used))
+		size, err := writeStream(outPath, stream.Body)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", outPath, err)
+		}
+		printSaved(cmd, outPath, size, stream.OriginalSize)
+		paths = append(paths, outPath)
+		return nil
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch screenshots: %w", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("failed to fetch screenshots: none received")
 	}
 
-	ref := fmt.Sprintf("@%s", outPath)
+	printRefs(cmd, paths, copyRef)
+	return nil
+}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "📸 Saved: %s (%d bytes)\n", outPath, len(data))
-	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ref)
+// watchScreenshots saves each new local screenshot until interrupted.
+func watchScreenshots(cmd *cobra.Command, c *client.Client, outputDir string, copyRef bool) error {
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Fprintln(cmd.ErrOrStderr(), "👀 Watching for new screenshots on your local machine (Ctrl-C to stop)")
+	used := map[string]bool{}
+	err := c.FetchFiles(cmd.Context(), "screenshots", func(stream *client.Stream) error {
+		outPath := filepath.Join(outputDir, screenshotFilename(stream, used))
+		size, err := writeStream(outPath, stream.Body)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", outPath, err)
+		}
+		printSaved(cmd, outPath, size, stream.OriginalSize)
+		printRefs(cmd, []string{outPath}, copyRef)
+		return nil
+	}, "watch")
+	if err != nil && cmd.Context().Err() != nil {
+		// Interrupted by the user.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("watching screenshots: %w", err)
+	}
+	return fmt.Errorf("watching screenshots: server closed the connection")
+}
+
+// screenshotFilename names a fetched screenshot after the time it was taken,
+// adding a counter when several share a second.
+func screenshotFilename(stream *client.Stream, used map[string]bool) string {
+	taken := stream.ModTime
+	if taken.IsZero() {
+		taken = time.Now()
+	}
+	ext := filepath.Ext(stream.Filename)
+	if ext == "" {
+		ext = ".png"
+	}
+
+	base := "screenshot-" + taken.Local().Format("20060102-150405")
+	name := base + ext
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	used[name] = true
+	return name
+}
+
+// printSaved reports a saved image, and how much smaller the server made it
+// when original is set.
+func printSaved(cmd *cobra.Command, path string, size, original int64) {
+	if original > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "📸 Saved: %s (%s, converted from %s)\n", path, formatSize(size), formatSize(original))
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "📸 Saved: %s (%s)\n", path, formatSize(size))
+}
+
+// printRefs prints an @ reference for each path and, if copyRef is set,
+// copies them to the clipboard separated by spaces.
+func printRefs(cmd *cobra.Command, paths []string, copyRef bool) {
+	refs := make([]string, len(paths))
+	for i, path := range paths {
+		refs[i] = "@" + path
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", refs[i])
+	}
 
 	if copyRef {
-		c2 := client.New()
-		if _, err := c2.SendCommand(cmd.Context(), "copy", ref); err != nil {
+		c := client.New()
+		if _, err := c.SendCommand(cmd.Context(), "copy", strings.Join(refs, " ")); err != nil {
 			fmt.Fprintf(cmd.ErrOrStderr(), "⚠️  Could not copy to clipboard: %v\n", err)
 		} else {
 			fmt.Fprintf(cmd.OutOrStdout(), "📋 Copied to clipboard\n")
 		}
 	}
+}
 
-	return nil
+// writeStream copies r into a new file at path, removing the partial file if
+// the transfer fails.
+func writeStream(path string, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+// imageFlags are the image conversion flags shared by screenshot and
+// clipboard-image.
+type imageFlags struct {
+	maxWidth int
+	format   string
+	quality  int
+}
+
+func (f *imageFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&f.maxWidth, imageutil.KeyMaxWidth, 0, "Scale images wider than this many `pixels` down on the local machine (0 for no limit)")
+	cmd.Flags().StringVar(&f.format, imageutil.KeyFormat, "", "Re-encode images as jpeg or png (default: keep the format)")
+	cmd.Flags().IntVar(&f.quality, imageutil.KeyQuality, 0, "JPEG quality, 1-100 (default 85)")
+}
+
+// newClient returns a client that asks the server for the conversions set on
+// the command line, after checking the server can do them. Flags left unset
+// keep the server's defaults.
+func (f *imageFlags) newClient(cmd *cobra.Command) (*client.Client, error) {
+	values := url.Values{}
+	if cmd.Flags().Changed(imageutil.KeyMaxWidth) {
+		values.Set(imageutil.KeyMaxWidth, strconv.Itoa(f.maxWidth))
+	}
+	if cmd.Flags().Changed(imageutil.KeyFormat) {
+		values.Set(imageutil.KeyFormat, f.format)
+	}
+	if cmd.Flags().Changed(imageutil.KeyQuality) {
+		values.Set(imageutil.KeyQuality, strconv.Itoa(f.quality))
+	}
+
+	query := values.Encode()
+	if _, err := (imageutil.Options{}).Apply(query); err != nil {
+		return nil, err
+	}
+
+	c := client.New()
+	if query != "" {
+		if err := c.RequireFeature(cmd.Context(), client.FeatureImageConvert, "image conversion"); err != nil {
+			return nil, err
+		}
+	}
+	c.SetImageOptions(query)
+	return c, nil
 }
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func newSendCmd() *cobra.Command {
+	var reveal, open bool
+
+	cmd := &cobra.Command{
+		Use:   "send <file>...",
+		Short: "Send files to the local machine",
+		Long: `Stream files from the remote machine through the gh-rdm tunnel into
+the local inbox (~/Downloads/gh-rdm unless the server was started with --inbox).
+Existing files are never overwritten; a numbered name is used instead.
+
+--open is subject to the server's open policy, which denies local files unless
+it is set to prompt. A file that can't be revealed or opened is still saved;
+the failures are reported once every file is sent.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if reveal && open {
+				return fmt.Errorf("use either --reveal or --open, not both")
+			}
+			action := ""
+			switch {
+			case reveal:
+				action = "reveal"
+			case open:
+				action = "open"
+			}
+
+			c := client.New()
+			if err := c.RequireFeature(cmd.Context(), client.FeatureStream, "sending files"); err != nil {
+				return err
+			}
+			if open {
+				// The server may ask the local user before opening.
+				c.SetTimeout(client.PromptTimeout + client.IdleTimeout)
+			}
+			// A failed reveal or open doesn't stop the remaining files;
+			// the failures are reported together at the end.
+			var failures []string
+			for _, path := range args {
+				result, err := sendFile(cmd, c, path, action)
+				if err != nil {
+					return fmt.Errorf("send %s: %w", path, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "📤 Sent %s → %s\n", path, result.Path)
+				if result.Error != "" {
+					failures = append(failures, fmt.Sprintf("%s: %s", result.Path, result.Error))
+				}
+			}
+			if len(failures) > 0 {
+				return errors.New(strings.Join(failures, "\n"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&reveal, "reveal", false, "Reveal the files in the local file manager")
+	cmd.Flags().BoolVar(&open, "open", false, "Open the files with their default local application")
+
+	return cmd
+}
+
+func sendFile(cmd *cobra.Command, c *client.Client, path, action string) (client.SendResult, error) {
+	var result client.SendResult
+	f, err := os.Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return result, err
+	}
+	if info.IsDir() {
+		return result, fmt.Errorf("is a directory")
+	}
+
+	data, err := c.SendStream(cmd.Context(), "send", f, info.Size(), filepath.Base(path), action)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result, nil
+}
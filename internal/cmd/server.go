@@ -1,18 +1,26 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/config"
 	"github.com/maxbeizer/gh-rdm/internal/hostservice"
+	"github.com/maxbeizer/gh-rdm/internal/imageutil"
 	"github.com/maxbeizer/gh-rdm/internal/server"
 	"github.com/spf13/cobra"
 )
 
 func newServerCmd(userMessages *log.Logger) *cobra.Command {
-	return &cobra.Command{
+	var inboxDir string
+	var fetchDirs []string
+	var historySize int
+	var historyFile string
+
+	cmd := &cobra.Command{
 		Use:   "server",
 		Short: "Start the gh-rdm server",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -32,11 +40,61 @@ func newServerCmd(userMessages *log.Logger) *cobra.Command {
 			}
 			defer logFile.Close()
 
-			svc := &hostservice.Service{}
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			svc := &hostservice.Service{
+				InboxDir:      inboxDir,
+				FetchDirs:     fetchDirs,
+				ScreenshotDir: cfg.Commands.ScreenshotDir,
+			}
+			host := &hostservice.CommandRunner{
+				Runner:                svc,
+				CopyCommand:           cfg.Commands.Copy,
+				PasteCommand:          cfg.Commands.Paste,
+				OpenCommand:           cfg.Commands.Open,
+				ClipboardImageCommand: cfg.Commands.ClipboardImage,
+			}
 			socketPath := client.UnixSocketPath()
-			srv := server.New(svc, socketPath, userMessages)
+			srv := server.New(host, socketPath, userMessages)
+			if err := srv.SetOpenPolicy(openPolicy(cfg.Open)); err != nil {
+				return err
+			}
+			if err := srv.SetImageDefaults(imageutil.Options(cfg.Images)); err != nil {
+				return fmt.Errorf("config images: %w", err)
+			}
+			if err := srv.SetHistoryLimit(historySize); err != nil {
+				return err
+			}
+			if historyFile != "" {
+				if err := srv.PersistHistory(historyFile); err != nil {
+					return err
+				}
+			}
 
 			return srv.Listen(cmd.Context())
 		},
 	}
+
+	cmd.Flags().StringVar(&inboxDir, "inbox", "", "Directory for files received with gh rdm send (default ~/Downloads/gh-rdm)")
+	cmd.Flags().IntVar(&historySize, "history-size", server.DefaultHistorySize, "Number of clipboard copies to remember (0 disables history)")
+	cmd.Flags().StringVar(&historyFile, "history-file", "", "Persist clipboard history to this file (default: memory only)")
+	cmd.Flags().StringArrayVar(&fetchDirs, "allow-dir", nil, "Directory gh rdm fetch may read from; repeatable (default ~/Downloads and ~/Desktop)")
+
+	return cmd
+}
+
+// openPolicy applies the config file's open settings to the default policy.
+func openPolicy(open config.Open) server.OpenPolicy {
+	policy := server.DefaultOpenPolicy()
+	if len(open.Schemes) > 0 {
+		policy.Schemes = open.Schemes
+	}
+	if open.Action != "" {
+		policy.Action = open.Action
+	}
+	policy.Hosts = open.Hosts
+	return policy
 }
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// States a tunnel session can be in.
+const (
+	sessionConnecting   = "connecting"
+	sessionForwarding   = "forwarding"
+	sessionReconnecting = "reconnecting"
+)
+
+// tunnelSession is the registry entry a running tunnel keeps in
+// ~/.gh-rdm/sessions/<target>.<pid>.json, where the target is a codespace or
+// SSH host. Keying by pid as well lets several tunnels to one target keep
+// their own entries. Removing the file asks the tunnel to stop.
+type tunnelSession struct {
+	Target     string    `json:"target"`
+	PID        int       `json:"pid"`
+	Started    time.Time `json:"started"`
+	Port       string    `json:"port,omitempty"`
+	State      string    `json:"state"`
+	Reconnects int       `json:"reconnects"`
+	LastError  string    `json:"last_error,omitempty"`
+	LastCheck  time.Time `json:"last_check,omitzero"`
+	Healthy    bool      `json:"healthy"`
+}
+
+func newSessionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sessions",
+		Short: "List running gh rdm tunnels",
+		Long: `List the tunnels started by gh rdm tunnel on this machine, with their
+target, process, uptime, remote port, state and last health check.
+
+Entries left behind by tunnels that are no longer running are removed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sessions, err := readSessions()
+			if err != nil {
+				return err
+			}
+			if len(sessions) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No tunnels running.")
+				return nil
+			}
+			printSessions(cmd.OutOrStdout(), sessions, time.Now())
+			return nil
+		},
+	}
+}
+
+func newTunnelStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop <codespace-or-host>",
+		Short: "Stop the tunnel to a codespace or SSH host",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return stopTunnel(cmd.OutOrStdout(), args[0])
+		},
+	}
+}
+
+// stopTunnel removes every session for name. Each tunnel notices within
+// sessionCheckInterval and disconnects.
+func stopTunnel(out io.Writer, name string) error {
+	entries, err := sessionEntries()
+	if err != nil {
+		return err
+	}
+
+	stopped := 0
+	for _, e := range entries {
+		if e.session.Target != name {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("stop tunnel: %w", err)
+		}
+		stopped++
+
+		if !processAlive(e.session.PID) {
+			fmt.Fprintf(out, "Removed stale tunnel entry for %q (pid %d was not running)\n", name, e.session.PID)
+			continue
+		}
+		fmt.Fprintf(out, "Stopping tunnel to %q (pid %d)\n", name, e.session.PID)
+	}
+	if stopped == 0 {
+		return fmt.Errorf("no tunnel to %q; see `gh rdm sessions`", name)
+	}
+	return nil
+}
+
+func printSessions(out io.Writer, sessions []tunnelSession, now time.Time) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tPID\tUPTIME\tPORT\tSTATE\tLAST CHECK")
+	for _, s := range sessions {
+		state := s.State
+		if s.State == sessionForwarding && !s.LastCheck.IsZero() && !s.Healthy {
+			state = "unhealthy"
+		}
+		if s.LastError != "" && s.State != sessionForwarding {
+			state += ": " + s.LastError
+		}
+		port := s.Port
+		if port == "" {
+			port = "-"
+		}
+		check := "-"
+		if !s.LastCheck.IsZero() {
+			check = formatAge(now.Sub(s.LastCheck))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", s.Target, s.PID, formatUptime(now.Sub(s.Started)), port, state, check)
+	}
+	tw.Flush()
+}
+
+// formatUptime renders d to the second, e.g. "1h5m0s".
+func formatUptime(d time.Duration) string {
+	return max(0, d).Truncate(time.Second).String()
+}
+
+func sessionsDir() (string, error) {
+	dir, err := client.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+func sessionPath(name string, pid int) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid tunnel name %q", name)
+	}
+	dir, err := sessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.%d.json", name, pid)), nil
+}
+
+// writeSession records s, replacing the file atomically so readers never
+// see a partial entry.
+func writeSession(s tunnelSession) error {
+	path, err := sessionPath(s.Target, s.PID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readSession(path string) (tunnelSession, error) {
+	var s tunnelSession
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// sessionEntry is a registry file and the session it holds.
+type sessionEntry struct {
+	path    string
+	session tunnelSession
+}
+
+// sessionEntries reads every registry file, skipping ones removed while
+// reading.
+func sessionEntries() ([]sessionEntry, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []sessionEntry
+	for _, path := range paths {
+		s, err := readSession(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, sessionEntry{path: path, session: s})
+	}
+	return entries, nil
+}
+
+// readSessions returns the registered tunnels sorted by target and pid,
+// removing entries whose process has exited.
+func readSessions() ([]tunnelSession, error) {
+	entries, err := sessionEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []tunnelSession
+	for _, e := range entries {
+		if !processAlive(e.session.PID) {
+			os.Remove(e.path)
+			continue
+		}
+		sessions = append(sessions, e.session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Target != sessions[j].Target {
+			return sessions[i].Target < sessions[j].Target
+		}
+		return sessions[i].PID < sessions[j].PID
+	})
+	return sessions, nil
+}
+
+func removeSession(name string, pid int) error {
+	path, err := sessionPath(name, pid)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func sessionExists(name string, pid int) bool {
+	path, err := sessionPath(name, pid)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRegistry(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	started := time.Now().Add(-time.Hour)
+	live := tunnelSession{Target: "live-space", PID: os.Getpid(), Started: started, State: sessionForwarding}
+	gone := tunnelSession{Target: "gone-space", PID: 1 << 30, Started: started, State: sessionForwarding}
+	for _, s := range []tunnelSession{live, gone} {
+		if err := writeSession(s); err != nil {
+			t.Fatalf("writeSession() error = %v", err)
+		}
+	}
+
+	sessions, err := readSessions()
+	if err != nil {
+		t.Fatalf("readSessions() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Target != "live-space" {
+		t.Fatalf("readSessions() = %+v, want only the live session", sessions)
+	}
+	if sessionExists("gone-space", gone.PID) {
+		t.Fatal("readSessions() kept the entry of an exited process")
+	}
+
+	var out bytes.Buffer
+	if err := stopTunnel(&out, "live-space"); err != nil {
+		t.Fatalf("stopTunnel() error = %v", err)
+	}
+	if sessionExists("live-space", live.PID) {
+		t.Fatal("stopTunnel() left the session in place")
+	}
+	if err := stopTunnel(&out, "live-space"); err == nil {
+		t.Fatal("stopTunnel() error = nil for an unknown tunnel")
+	}
+}
+
+func TestSessionRegistryKeepsTunnelsToOneTarget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	first := tunnelSession{Target: "shared-space", PID: os.Getpid(), State: sessionForwarding}
+	second := tunnelSession{Target: "shared-space", PID: os.Getppid(), State: sessionForwarding}
+	for _, s := range []tunnelSession{first, second} {
+		if err := writeSession(s); err != nil {
+			t.Fatalf("writeSession() error = %v", err)
+		}
+	}
+	if sessions, err := readSessions(); err != nil || len(sessions) != 2 {
+		t.Fatalf("readSessions() = %+v, %v, want both tunnels", sessions, err)
+	}
+
+	if err := removeSession(first.Target, first.PID); err != nil {
+		t.Fatalf("removeSession() error = %v", err)
+	}
+	if !sessionExists(second.Target, second.PID) {
+		t.Fatal("removeSession() removed the other tunnel's entry")
+	}
+
+	writeSession(first)
+	var out bytes.Buffer
+	if err := stopTunnel(&out, "shared-space"); err != nil {
+		t.Fatalf("stopTunnel() error = %v", err)
+	}
+	if sessionExists(first.Target, first.PID) || sessionExists(second.Target, second.PID) {
+		t.Fatalf("stopTunnel() left a tunnel to the target running:\n%s", out.String())
+	}
+}
+
+func TestSessionPathRejectsTraversal(t *testing.T) {
+	for _, name := range []string{"", "..", "../token", "a/b"} {
+		if _, err := sessionPath(name, 1); err == nil {
+			t.Errorf("sessionPath(%q) error = nil", name)
+		}
+	}
+}
+
+func TestPrintSessions(t *testing.T) {
+	now := time.Date(2026, 3, 6, 12, 0, 0, 0, time.UTC)
+	sessions := []tunnelSession{
+		{Target: "alpha", PID: 42, Started: now.Add(-90 * time.Minute), Port: "24817", State: sessionForwarding, Healthy: true, LastCheck: now.Add(-3 * time.Second)},
+		{Target: "beta", PID: 43, Started: now.Add(-time.Minute), State: sessionReconnecting, LastError: "exit status 255"},
+	}
+
+	var out bytes.Buffer
+	printSessions(&out, sessions, now)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printSessions() lines = %d, want 3:\n%s", len(lines), out.String())
+	}
+	for _, want := range []string{"alpha", "42", "1h30m0s", "24817", "forwarding", "just now"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("printSessions() line missing %q: %q", want, lines[1])
+		}
+	}
+	if !strings.Contains(lines[2], "reconnecting: exit status 255") || !strings.HasSuffix(lines[2], "-") {
+		t.Fatalf("printSessions() line = %q, want state with reason and no check", lines[2])
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -25,39 +26,20 @@ func newSetupCmd() *cobra.Command {
 
 			// Step 1: Server Status
 			fmt.Fprintln(out, "=== Step 1: Server Status ===")
-			c := client.New()
-			_, err := c.SendCommand(ctx, "status")
-			if err == nil {
+			// The status check only passes with the running server's token,
+			// so once it does the token file is current.
+			socketPath := client.UnixSocketPath()
+			deps := defaultTunnelDeps()
+			running := deps.statusUnix(ctx, socketPath) == nil
+			if running {
 				fmt.Fprintln(out, "✓ Server is already running")
-			} else {
-				if askYesNo(scanner, "Start the server now? [Y/n]") {
-					socketPath := client.UnixSocketPath()
-					homeDir, err := os.UserHomeDir()
-					if err != nil {
-						return err
-					}
-					logDir := filepath.Join(homeDir, ".gh-rdm")
-					if err := os.MkdirAll(logDir, 0o755); err != nil {
-						return err
-					}
-					logFile, err := os.OpenFile(filepath.Join(logDir, "server.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-					if err != nil {
-						return err
-					}
-
-					srvCmd := exec.CommandContext(ctx, os.Args[0], "server")
-					srvCmd.Stdout = logFile
-					srvCmd.Stderr = logFile
-					if err := srvCmd.Start(); err != nil {
-						logFile.Close()
-						return fmt.Errorf("failed to start server: %w", err)
-					}
-					logFile.Close()
-
-					fmt.Fprintf(out, "✓ Server started (socket: %s)\n", socketPath)
-				} else {
-					fmt.Fprintln(out, "Skipping. Run `gh rdm server` when ready.")
+			} else if askYesNo(scanner, "Start the server now? [Y/n]") {
+				if err := ensureLocalServer(ctx, out, socketPath, deps); err != nil {
+					return err
 				}
+				running = true
+			} else {
+				fmt.Fprintln(out, "Skipping. Run `gh rdm server` when ready.")
 			}
 
 			// Step 2: SSH Config
@@ -67,9 +49,15 @@ func newSetupCmd() *cobra.Command {
 				scanner.Scan()
 				hostName := strings.TrimSpace(scanner.Text())
 				if hostName != "" {
-					if err := configureSSH(out, hostName); err != nil {
+					port := client.ConfiguredPort()
+					if err := configureSSH(out, hostName, port); err != nil {
 						fmt.Fprintf(out, "Warning: %v\n", err)
 					}
+					token := ""
+					if running {
+						token = deps.readToken()
+					}
+					copySessionToken(ctx, out, scanner, hostName, port, token)
 				}
 			}
 
@@ -130,7 +118,9 @@ func askChoice(scanner *bufio.Scanner, prompt string, options []string) int {
 	return choice
 }
 
-func configureSSH(out io.Writer, hostName string) error {
+// configureSSH adds a RemoteForward from remote port to the server socket
+// for hostName to ~/.ssh/config.
+func configureSSH(out io.Writer, hostName, port string) error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return err
@@ -138,7 +128,7 @@ func configureSSH(out io.Writer, hostName string) error {
 
 	socketPath := client.UnixSocketPath()
 	sshConfigPath := filepath.Join(homeDir, ".ssh", "config")
-	remoteForwardLine := fmt.Sprintf("    RemoteForward localhost:7391 %s", socketPath)
+	remoteForwardLine := fmt.Sprintf("    RemoteForward localhost:%s %s", port, socketPath)
 	exitOnForwardFailureLine := "    ExitOnForwardFailure yes"
 
 	data, err := os.ReadFile(sshConfigPath)
@@ -162,8 +152,15 @@ func configureSSH(out io.Writer, hostName string) error {
 	hostPattern := regexp.MustCompile(`(?im)^Host\s+` + regexp.QuoteMeta(hostName) + `\s*$`)
 	if hostPattern.MatchString(content) {
 		// Host block exists
-		rfPattern := regexp.MustCompile(`(?i)RemoteForward.*gh-rdm`)
-		if rfPattern.MatchString(content) {
+		rfPattern := regexp.MustCompile(`(?i)RemoteForward.*gh-rdm.*`)
+		if existing := rfPattern.FindString(content); existing != "" {
+			if !strings.Contains(existing, socketPath) {
+				fmt.Fprintf(out, "⚠ SSH config for host '%s' forwards a different gh-rdm socket:\n", hostName)
+				fmt.Fprintf(out, "  %s\n", strings.TrimSpace(existing))
+				fmt.Fprintln(out, "  Replace it with:")
+				fmt.Fprintf(out, "  %s\n", remoteForwardLine)
+				return nil
+			}
 			fmt.Fprintf(out, "✓ SSH config for host '%s' already has RemoteForward for gh-rdm\n", hostName)
 			return nil
 		}
@@ -189,6 +186,25 @@ func configureSSH(out io.Writer, hostName string) error {
 	return nil
 }
 
+// copySessionToken offers to hand hostName the running server's session
+// token and port, and prints the command that does it by hand. token is
+// empty when no server is running.
+func copySessionToken(ctx context.Context, out io.Writer, scanner *bufio.Scanner, hostName, port, token string) {
+	if token == "" {
+		fmt.Fprintln(out, "⚠ No session token yet; once the server is running, copy it to the host with:")
+	} else if askYesNo(scanner, fmt.Sprintf("Copy the session token to '%s' now? [Y/n]", hostName)) {
+		if err := sendToken(ctx, token, "ssh", hostName, remoteTokenScript(port)); err != nil {
+			fmt.Fprintf(out, "Warning: could not copy session token: %v\n", err)
+		} else {
+			fmt.Fprintf(out, "✓ Copied session token to '%s'\n", hostName)
+		}
+		fmt.Fprintln(out, "  The token changes whenever the server restarts. To copy it again, run:")
+	} else {
+		fmt.Fprintln(out, "  Remote commands need the session token. Copy it to the host with:")
+	}
+	fmt.Fprintf(out, "  gh rdm token | ssh %s '%s'\n", hostName, remoteTokenScript(port))
+}
+
 func printNeovimConfig(out io.Writer) {
 	fmt.Fprintln(out, "\n-- Add to your init.lua:")
 	fmt.Fprintln(out, `vim.g.clipboard = {
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigureSSHUsesGivenPort(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	// A port file left by a tunnel into this machine must not leak into the
+	// forward set up from here.
+	if err := os.MkdirAll(filepath.Join(home, ".gh-rdm"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gh-rdm", "port"), []byte("24817\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := configureSSH(&bytes.Buffer{}, "devvm", "7400"); err != nil {
+		t.Fatalf("configureSSH() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".ssh", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "RemoteForward localhost:7400 ") {
+		t.Fatalf("ssh config missing forward from port 7400:\n%s", data)
+	}
+}
+
+func TestCopySessionTokenWithoutRunningServer(t *testing.T) {
+	var out bytes.Buffer
+	copySessionToken(context.Background(), &out, bufio.NewScanner(strings.NewReader("")), "devvm", "7391", "")
+
+	if !strings.Contains(out.String(), "No session token yet") || !strings.Contains(out.String(), "gh rdm token | ssh devvm") {
+		t.Fatalf("copySessionToken() output without a server:\n%s", out.String())
+	}
+}
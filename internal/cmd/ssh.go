@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// ExitCodeError carries the exit status of a command gh rdm ran in the
+// foreground, such as an ssh session, so the process can exit with it
+// without printing anything more.
+type ExitCodeError struct {
+	Code int
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func newSSHCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ssh <host> [-- ssh-args...]",
+		Short: "Open an SSH session with gh-rdm forwarding attached",
+		Long: `Open an interactive SSH session to host with the gh-rdm forward added, so
+no separate tunnel is needed. The local server is started if needed and the
+session token is handed over first.
+
+The forward lasts as long as the session. Unless --port is set, the remote sshd
+picks a free port and gh-rdm records it on the remote side once the forward is
+up. If a fixed port is already taken, for example by another session's
+forward, a warning is printed and the session continues without it. Arguments
+after -- are passed to ssh.`,
+		Example: `  gh rdm ssh devvm
+  gh rdm ssh devvm -- -J bastion -p 2222`,
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 && dash != 1 {
+				return fmt.Errorf("give one host before --")
+			} else if dash < 0 && len(args) > 1 {
+				return fmt.Errorf("put ssh arguments after --, e.g. gh rdm ssh %s -- %s", args[0], strings.Join(args[1:], " "))
+			}
+			target := tunnelTarget{name: args[0], ssh: true, sshArgs: args[1:]}
+			return runSSHSession(cmd.Context(), cmd.ErrOrStderr(), target, defaultTunnelDeps())
+		},
+	}
+}
+
+func newCSCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cs",
+		Short: "Work with GitHub Codespaces",
+	}
+	cmd.AddCommand(newCSSSHCmd())
+	return cmd
+}
+
+func newCSSSHCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ssh [codespace]",
+		Short: "Open a codespace shell with gh-rdm forwarding attached",
+		Long: `Open an interactive gh cs ssh session with the gh-rdm forward added, so no
+separate tunnel is needed. Without a name, the only codespace is used, or one is
+picked interactively.
+
+The forward lasts as long as the session. Unless --port is set, the remote sshd
+picks a free port and gh-rdm records it on the remote side once the forward is
+up. If a fixed port is already taken, a warning is printed and the session
+continues without it.`,
+		Example: `  gh rdm cs ssh
+  gh rdm cs ssh my-space`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deps := defaultTunnelDeps()
+			requested := ""
+			if len(args) == 1 {
+				requested = args[0]
+			}
+			name, err := resolveCodespace(cmd.Context(), requested, deps.listCodespaces, deps.pickCodespace)
+			if err != nil {
+				return err
+			}
+			return runSSHSession(cmd.Context(), cmd.ErrOrStderr(), codespaceTarget(name), deps)
+		},
+	}
+}
+
+// sessionForwardOptions are the ssh options for an interactive session with
+// the socket forwarded from remote port. A taken port only costs the forward,
+// not the session. Multiplexing is off so the forward belongs to this
+// connection and ends at logout instead of living on in a shared master.
+func sessionForwardOptions(socketPath, port string) []string {
+	options := []string{
+		"-o", "ExitOnForwardFailure=no",
+		"-o", "ControlMaster=no", "-o", "ControlPath=none",
+	}
+	return append(options, forwardOptions(socketPath, port)...)
+}
+
+// runSSHSession starts the local server if needed, hands target the session
+// token and remote port, and opens an interactive session with the forward attached.
+func runSSHSession(ctx context.Context, out io.Writer, target tunnelTarget, deps tunnelDeps) error {
+	socketPath := deps.socketPath()
+	if err := ensureLocalServer(ctx, out, socketPath, deps); err != nil {
+		return err
+	}
+
+	token := deps.readToken()
+	if token == "" {
+		return errors.New("local server did not publish a session token; restart it with `gh rdm stop && gh rdm server`")
+	}
+	target.port = deps.remotePort()
+	fmt.Fprintf(out, "Sending session token to %s\n", target)
+	if err := deps.sendToken(ctx, target, token); err != nil {
+		return fmt.Errorf("send session token: %w", err)
+	}
+	if target.port == dynamicPort {
+		fmt.Fprintf(out, "Forwarding a remote port to %s for this session\n", socketPath)
+	} else {
+		fmt.Fprintf(out, "Forwarding localhost:%s to %s for this session\n", target.port, socketPath)
+	}
+
+	// The session owns the terminal by the time the port is allocated, so
+	// warnings end in \r\n.
+	err := deps.runSession(ctx, target, sessionForwardOptions(socketPath, target.port), func(port string) {
+		if target.port != dynamicPort {
+			return
+		}
+		if err := deps.recordPort(ctx, target, port); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(out, "⚠️  Could not record remote port %s on %s, so gh-rdm commands there won't find this machine: %v\r\n", port, target, err)
+			fmt.Fprintf(out, "   Recording it needs a second connection without a password prompt. Run `echo %s > ~/.gh-rdm/port` there, or use --port to fix one.\r\n", port)
+		}
+	})
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return &ExitCodeError{Code: exitErr.ExitCode()}
+	}
+	return err
+}
+
+// runInteractive connects to target with options, attached to the terminal,
+// and watches ssh's stderr for the remote forward's outcome.
+func runInteractive(ctx context.Context, target tunnelTarget, options []string, forwarding func(string)) error {
+	argv := target.command(options, "")
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = &forwardWatcher{w: os.Stderr, target: target, allocated: forwarding}
+	return cmd.Run()
+}
+
+// allocatedPort matches ssh's report of the port the remote sshd picked for
+// a dynamicPort forward.
+var allocatedPort = regexp.MustCompile(`Allocated port (\d+) for remote forward`)
+
+// forwardWatcher passes ssh's stderr through. It calls allocated, in its own
+// goroutine, with the port the remote sshd picked for a dynamicPort forward,
+// and explains ssh's warning when a fixed remote port is already taken.
+type forwardWatcher struct {
+	w         io.Writer
+	target    tunnelTarget
+	allocated func(string)
+
+	mu     sync.Mutex
+	line   []byte
+	warned bool
+}
+
+func (f *forwardWatcher) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.line = append(f.line, p...)
+	for {
+		i := bytes.IndexByte(f.line, '\n')
+		if i < 0 {
+			break
+		}
+		if m := allocatedPort.FindSubmatch(f.line[:i]); m != nil && f.target.port == dynamicPort && f.allocated != nil {
+			go f.allocated(string(m[1]))
+		}
+		if !f.warned && f.target.port != dynamicPort && bytes.Contains(f.line[:i], []byte("remote port forwarding failed")) {
+			f.warned = true
+			fmt.Fprintf(f.w, "⚠️  Remote port %s is already in use on %s, so gh-rdm commands won't reach this machine from this session. Use --port to pick another.\r\n", f.target.port, f.target)
+		}
+		f.line = f.line[i+1:]
+	}
+	return n, err
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+)
+
+func TestRunSSHSessionAttachesForward(t *testing.T) {
+	t.Setenv(client.PortEnv, "")
+
+	var out bytes.Buffer
+	var steps []string
+	var options []string
+	deps := fakeTunnelDeps()
+	deps.startServer = func() error {
+		steps = append(steps, "server")
+		return nil
+	}
+	deps.statusUnix = func(context.Context, string) error {
+		if len(steps) == 0 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	deps.sendToken = func(_ context.Context, target tunnelTarget, token string) error {
+		steps = append(steps, "token "+target.name+" "+token)
+		return nil
+	}
+	deps.runSession = func(_ context.Context, target tunnelTarget, opts []string, _ func(string)) error {
+		steps = append(steps, "session "+target.name)
+		options = opts
+		return nil
+	}
+
+	target := tunnelTarget{name: "devvm", ssh: true}
+	if err := runSSHSession(context.Background(), &out, target, deps); err != nil {
+		t.Fatalf("runSSHSession() error = %v", err)
+	}
+
+	want := []string{"server", "token devvm session-token", "session devvm"}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("runSSHSession() steps = %q, want %q", steps, want)
+	}
+	got := strings.Join(options, " ")
+	for _, want := range []string{"ExitOnForwardFailure=no", "ControlPath=none", "-R localhost:7391:/tmp/gh-rdm.sock"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("runSSHSession() options = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestRunSSHSessionPassesExitCode(t *testing.T) {
+	deps := fakeTunnelDeps()
+	deps.runSession = func(context.Context, tunnelTarget, []string, func(string)) error {
+		return exec.Command("sh", "-c", "exit 3").Run()
+	}
+
+	err := runSSHSession(context.Background(), &bytes.Buffer{}, codespaceTarget("my-space"), deps)
+	var exitErr *ExitCodeError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("runSSHSession() error = %v, want exit code 3", err)
+	}
+}
+
+func TestForwardWatcherWarnsAboutTakenPort(t *testing.T) {
+	var out bytes.Buffer
+	w := &forwardWatcher{w: &out, target: tunnelTarget{name: "devvm", ssh: true, port: "7391"}}
+
+	w.Write([]byte("Warning: remote port forwarding fai"))
+	w.Write([]byte("led for listen port 7391\r\n"))
+	w.Write([]byte("Warning: remote port forwarding failed for listen port 7391\r\n"))
+
+	if got := strings.Count(out.String(), "already in use on host \"devvm\""); got != 1 {
+		t.Fatalf("watcher warned %d times, want once:\n%s", got, out.String())
+	}
+	if !strings.HasPrefix(out.String(), "Warning: remote port forwarding failed") {
+		t.Fatalf("watcher did not pass ssh's output through:\n%s", out.String())
+	}
+}
+
+func TestForwardWatcherReportsAllocatedPort(t *testing.T) {
+	var out bytes.Buffer
+	allocated := make(chan string, 1)
+	w := &forwardWatcher{w: &out, target: tunnelTarget{name: "devvm", ssh: true, port: dynamicPort}, allocated: func(port string) {
+		allocated <- port
+	}}
+
+	w.Write([]byte("Allocated port 41235 for remote forward to /tmp/gh-rdm.sock:0\r\n"))
+
+	select {
+	case port := <-allocated:
+		if port != "41235" {
+			t.Fatalf("watcher reported port %q, want 41235", port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not report the allocated port")
+	}
+	if strings.Contains(out.String(), "already in use") {
+		t.Fatalf("watcher warned about a dynamic port:\n%s", out.String())
+	}
+}
+
+func TestRunSSHSessionRecordsAllocatedPort(t *testing.T) {
+	var recorded string
+	var options []string
+	deps := fakeTunnelDeps()
+	deps.remotePort = func() string { return dynamicPort }
+	deps.recordPort = func(_ context.Context, _ tunnelTarget, port string) error {
+		recorded = port
+		return nil
+	}
+	deps.runSession = func(_ context.Context, _ tunnelTarget, opts []string, forwarding func(string)) error {
+		options = opts
+		forwarding("41235")
+		return nil
+	}
+
+	if err := runSSHSession(context.Background(), &bytes.Buffer{}, tunnelTarget{name: "devvm", ssh: true}, deps); err != nil {
+		t.Fatalf("runSSHSession() error = %v", err)
+	}
+	if recorded != "41235" {
+		t.Fatalf("runSSHSession() recorded port %q, want the allocated 41235", recorded)
+	}
+	if got := strings.Join(options, " "); !strings.Contains(got, "-R localhost:0:/tmp/gh-rdm.sock") {
+		t.Fatalf("runSSHSession() options = %q, want a dynamic forward", got)
+	}
+}
+
+func TestRunSSHSessionExplainsUnrecordedPort(t *testing.T) {
+	var out bytes.Buffer
+	deps := fakeTunnelDeps()
+	deps.remotePort = func() string { return dynamicPort }
+	deps.recordPort = func(context.Context, tunnelTarget, string) error {
+		return errors.New("Permission denied (keyboard-interactive)")
+	}
+	deps.runSession = func(_ context.Context, _ tunnelTarget, _ []string, forwarding func(string)) error {
+		forwarding("41235")
+		return nil
+	}
+
+	if err := runSSHSession(context.Background(), &out, tunnelTarget{name: "devvm", ssh: true}, deps); err != nil {
+		t.Fatalf("runSSHSession() error = %v", err)
+	}
+	for _, want := range []string{"Could not record remote port 41235", "echo 41235 > ~/.gh-rdm/port"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("runSSHSession() output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// remoteTokenScript stores the token read from stdin where remote clients of
+// port look for it. The default port is recorded as the one the tunnel
+// forwards; a port the remote sshd has yet to allocate is left for
+// remotePortScript to record once the forward is up. Any other fixed port
+// belongs to a setup of its own, whose clients set the same port and read
+// their own token file, so it leaves the port file to the default setup.
+func remoteTokenScript(port string) string {
+	script := "umask 077 && mkdir -p ~/.gh-rdm && cat > ~/.gh-rdm/"
+	switch key := client.PortTokenKey(port); {
+	case port == dynamicPort:
+		return script + "token"
+	case key == "":
+		return script + "token && echo " + port + " > ~/.gh-rdm/port"
+	default:
+		return script + "token-" + key
+	}
+}
+
+// remotePortScript records port as the one the tunnel forwards.
+func remotePortScript(port string) string {
+	return "umask 077 && mkdir -p ~/.gh-rdm && echo " + port + " > ~/.gh-rdm/port"
+}
+
+// remotePortRecorder is the remote command of a dynamicPort tunnel. It
+// records the port written to its stdin once the forward is up, then holds
+// the connection open until the tunnel closes stdin.
+const remotePortRecorder = "read port && umask 077 && mkdir -p ~/.gh-rdm && echo $port > ~/.gh-rdm/port; exec cat > /dev/null"
+
+func newTokenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "token",
+		Short: "Print the session token of the running server",
+		Long: `Print the session token of the running local server.
+
+Remote clients must present this token. gh rdm tunnel and gh rdm setup copy it
+to the remote host for you, along with the port to connect on; to do it by
+hand, run:
+
+  gh rdm token | ssh <host> '` + remoteTokenScript(strconv.Itoa(client.DefaultPort)) + `'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := client.ReadToken(client.SocketTokenKey(client.UnixSocketPath()))
+			if token == "" {
+				return errors.New("no session token found; start the server with `gh rdm server`")
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), token)
+			return nil
+		},
+	}
+}
+
+// sendToken runs name with args, feeding token on stdin. The command is
+// expected to end in remoteTokenScript on the remote host.
+func sendToken(ctx context.Context, token string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(token + "\n")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
@@ -16,8 +20,9 @@ import (
 )
 
 type codespace struct {
-	Name  string `json:"name"`
-	State string `json:"state"`
+	Name       string `json:"name"`
+	State      string `json:"state"`
+	Repository string `json:"repository"`
 }
 
 type tunnelDeps struct {
@@ -25,41 +30,196 @@ type tunnelDeps struct {
 	statusUnix     func(context.Context, string) error
 	startServer    func() error
 	listCodespaces func(context.Context) ([]codespace, error)
-	runTunnel      func(context.Context, string, string) error
+	// pickCodespace asks the user to choose when several codespaces exist.
+	// nil means there is no terminal to ask on.
+	pickCodespace func(context.Context, []codespace) (string, error)
+	readToken     func() string
+	remotePort    func() string
+	sendToken     func(context.Context, tunnelTarget, string) error
+	// recordPort writes the port the remote sshd allocated for an
+	// interactive session to the remote port file.
+	recordPort func(context.Context, tunnelTarget, string) error
+	// runTunnel calls its last argument with the remote port once the
+	// forward is up, having recorded a port the remote sshd allocated over
+	// the same connection. runSession calls it with the port the remote
+	// sshd allocated for a dynamicPort forward.
+	runTunnel     func(context.Context, tunnelTarget, string, func(string)) error
+	runSession    func(context.Context, tunnelTarget, []string, func(string)) error
+	now           func() time.Time
+	sleep         func(context.Context, time.Duration) error
+	saveSession   func(tunnelSession) error
+	sessionExists func(string, int) bool
+	removeSession func(string, int) error
+}
+
+// tunnelOptions are the gh rdm tunnel flags and arguments.
+type tunnelOptions struct {
+	// codespaces to connect to. Empty means pick one, or all available ones
+	// with all.
+	codespaces []string
+	all        bool
+	once       bool
+	// sshHost is a plain SSH host to connect to instead of codespaces, with
+	// extra ssh arguments in sshArgs.
+	sshHost string
+	sshArgs []string
+}
+
+// tunnelTarget is a machine the tunnel forwards the socket to: a codespace,
+// reached through gh cs ssh, or a host reached with plain ssh.
+type tunnelTarget struct {
+	name string
+	ssh  bool
+	// sshArgs are extra ssh arguments for a plain SSH host, such as -J or -p.
+	sshArgs []string
+	// port is the remote port forwarded on the current connection, or
+	// dynamicPort to have the remote sshd pick one.
+	port string
+}
+
+func codespaceTarget(name string) tunnelTarget {
+	return tunnelTarget{name: name}
+}
+
+func (t tunnelTarget) String() string {
+	if t.ssh {
+		return fmt.Sprintf("host %q", t.name)
+	}
+	return fmt.Sprintf("codespace %q", t.name)
+}
+
+// command returns the command line that connects to t with the given ssh
+// options and runs remote there, if set.
+func (t tunnelTarget) command(options []string, remote string) []string {
+	var argv []string
+	if t.ssh {
+		argv = append([]string{"ssh"}, t.sshArgs...)
+		argv = append(argv, options...)
+		argv = append(argv, t.name)
+	} else {
+		argv = append([]string{"gh", "cs", "ssh", "-c", t.name, "--"}, options...)
+	}
+	if remote != "" {
+		argv = append(argv, remote)
+	}
+	return argv
+}
+
+// tunnelCommand returns the ssh command that forwards target's remote port
+// to socketPath. Several tunnels run at once, so none of them reads the
+// terminal: ssh asks for passwords on /dev/tty. A fixed port needs no remote
+// command and gets no stdin. For a dynamicPort forward, remotePortRecorder
+// reads the allocated port from stdin, so it is recorded over this
+// connection instead of a second one that might need a password.
+func tunnelCommand(ctx context.Context, target tunnelTarget, socketPath string, forwarding func(string)) (*exec.Cmd, error) {
+	// Keepalives make ssh notice a connection that died while the
+	// laptop slept, so the tunnel can be restarted.
+	options := []string{"-o", "ExitOnForwardFailure=yes", "-o", "ServerAliveInterval=15", "-o", "ServerAliveCountMax=3"}
+	remote := remotePortRecorder
+	if target.port != dynamicPort {
+		options = append(options, "-N")
+		remote = ""
+	}
+	argv := target.command(append(options, forwardOptions(socketPath, target.port)...), remote)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout = os.Stdout
+
+	allocated := forwarding
+	if target.port == dynamicPort {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		allocated = func(port string) {
+			forwarding(port)
+			if _, err := fmt.Fprintln(stdin, port); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not record remote port %s on %s: %v; use --port to fix one instead\n", port, target, err)
+			}
+		}
+	}
+	cmd.Stderr = &forwardWatcher{w: os.Stderr, target: target, allocated: allocated}
+	return cmd, nil
 }
 
 func newTunnelCmd() *cobra.Command {
 	var codespaceName string
+	var opts tunnelOptions
 
 	cmd := &cobra.Command{
-		Use:   "tunnel [codespace]",
-		Short: "Start a GitHub Codespaces tunnel to the local gh-rdm server",
-		Args:  cobra.MaximumNArgs(1),
+		Use:   "tunnel [codespace...]",
+		Short: "Start a GitHub Codespaces or SSH tunnel to the local gh-rdm server",
+		Long: `Start a GitHub Codespaces tunnel to the local gh-rdm server.
+
+With --ssh, tunnel to a plain SSH host instead. Arguments after -- are passed
+to ssh, for example -J for a jump host, -i for an identity file or -p for a
+port.
+
+The tunnel reconnects whenever the connection drops, for example after the
+laptop sleeps, waiting longer after each failed attempt. Before each attempt it
+makes sure the local server is running and sends the remote side its current
+session token. Use --once to exit when the first connection ends instead.
+
+Unless --port is set, each connection forwards a free remote port picked by
+the remote sshd and, once the forward is up, records it in ~/.gh-rdm/port on
+the remote side, where gh rdm commands look for it. Users sharing a host then
+don't collide.
+
+Name several codespaces, or pass --all for every available one, to serve them
+all from one process. List running tunnels with gh rdm sessions and end one
+with gh rdm tunnel stop <codespace>.`,
+		Example: `  gh rdm tunnel my-space
+  gh rdm tunnel --all
+  gh rdm tunnel --ssh devvm -- -J bastion -p 2222
+  gh rdm tunnel stop my-space`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 1 {
-				if codespaceName != "" {
+			opts.codespaces = args
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				opts.codespaces, opts.sshArgs = args[:dash], args[dash:]
+				if opts.sshHost == "" && len(opts.sshArgs) > 0 {
+					return fmt.Errorf("arguments after -- are ssh options and need --ssh <host>")
+				}
+			}
+			if opts.sshHost != "" && (len(opts.codespaces) > 0 || opts.all || codespaceName != "") {
+				return fmt.Errorf("--ssh cannot be combined with codespaces")
+			}
+			if codespaceName != "" {
+				if len(opts.codespaces) > 0 {
 					return fmt.Errorf("provide a codespace either as an argument or with --codespace, not both")
 				}
-				codespaceName = args[0]
+				opts.codespaces = []string{codespaceName}
+			}
+			if opts.all && len(opts.codespaces) > 0 {
+				return fmt.Errorf("--all cannot be combined with codespace names")
 			}
-			return runTunnel(cmd.Context(), cmd.OutOrStdout(), codespaceName, defaultTunnelDeps())
+			return runTunnel(cmd.Context(), cmd.OutOrStdout(), opts, defaultTunnelDeps())
 		},
 	}
 
 	cmd.Flags().StringVarP(&codespaceName, "codespace", "c", "", "Codespace name to connect to")
+	cmd.Flags().BoolVar(&opts.all, "all", false, "Connect to every available codespace")
+	cmd.Flags().BoolVar(&opts.once, "once", false, "Exit when the connection ends instead of reconnecting")
+	cmd.Flags().StringVar(&opts.sshHost, "ssh", "", "Tunnel to this SSH host instead of a codespace")
+
+	cmd.AddCommand(newTunnelStopCmd())
 
 	return cmd
 }
 
 func defaultTunnelDeps() tunnelDeps {
+	var pick func(context.Context, []codespace) (string, error)
+	if isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		pick = pickCodespaceInteractive
+	}
+
 	return tunnelDeps{
 		socketPath: client.UnixSocketPath,
 		statusUnix: func(ctx context.Context, socketPath string) error {
-			return checkStatus(ctx, client.NewWithSocketPath(socketPath))
+			_, err := fetchStatus(ctx, client.NewWithSocketPath(socketPath))
+			return err
 		},
 		startServer: startServerInBackground,
 		listCodespaces: func(ctx context.Context) ([]codespace, error) {
-			cmd := exec.CommandContext(ctx, "gh", "cs", "list", "--json", "name,state")
+			cmd := exec.CommandContext(ctx, "gh", "cs", "list", "--json", "name,state,repository")
 			output, err := cmd.CombinedOutput()
 			if err != nil {
 				return nil, fmt.Errorf("list codespaces: %w: %s", err, strings.TrimSpace(string(output)))
@@ -71,39 +231,352 @@ func defaultTunnelDeps() tunnelDeps {
 			}
 			return codespaces, nil
 		},
-		runTunnel: func(ctx context.Context, codespaceName, socketPath string) error {
-			forward := fmt.Sprintf("localhost:%s:%s", rdmTunnelPort, socketPath)
-			cmd := exec.CommandContext(ctx, "gh", "cs", "ssh", "-c", codespaceName, "--", "-o", "ExitOnForwardFailure=yes", "-N", "-R", forward)
-			cmd.Stdin = os.Stdin
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
+		pickCodespace: pick,
+		readToken: func() string {
+			return client.ReadToken(client.SocketTokenKey(client.UnixSocketPath()))
+		},
+		remotePort: remotePort,
+		sendToken: func(ctx context.Context, target tunnelTarget, token string) error {
+			argv := target.command(nil, remoteTokenScript(target.port))
+			return sendToken(ctx, token, argv[0], argv[1:]...)
+		},
+		recordPort: func(ctx context.Context, target tunnelTarget, port string) error {
+			// The session owns the terminal, so there is no one to answer
+			// a password prompt.
+			argv := target.command([]string{"-o", "BatchMode=yes"}, remotePortScript(port))
+			output, err := exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+			}
+			return nil
+		},
+		runTunnel: func(ctx context.Context, target tunnelTarget, socketPath string, forwarding func(string)) error {
+			cmd, err := tunnelCommand(ctx, target, socketPath, forwarding)
+			if err != nil {
+				return fmt.Errorf("run tunnel: %w", err)
+			}
+			if target.port != dynamicPort {
+				forwarding(target.port)
+			}
 			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("run codespaces tunnel: %w", err)
+				return fmt.Errorf("run tunnel: %w", err)
 			}
 			return nil
 		},
+		runSession:    runInteractive,
+		now:           time.Now,
+		sleep:         sleepContext,
+		saveSession:   writeSession,
+		sessionExists: sessionExists,
+		removeSession: removeSession,
+	}
+}
+
+// Reconnect backoff for a supervised tunnel. The delay doubles after each
+// failed attempt and starts over once a connection has stayed up for
+// tunnelStableAfter.
+const (
+	tunnelMinBackoff  = time.Second
+	tunnelMaxBackoff  = time.Minute
+	tunnelStableAfter = time.Minute
+)
+
+// dynamicPort asks the remote sshd to forward a free port of its choosing.
+const dynamicPort = "0"
+
+// remotePort returns the remote port for the next connection: the configured
+// one, or dynamicPort so that users sharing a host don't fight over a single
+// port. A fresh port per connection also avoids waiting for the remote sshd
+// to release the previous connection's listener.
+func remotePort() string {
+	if port := os.Getenv(client.PortEnv); port != "" {
+		return port
 	}
+	return dynamicPort
 }
 
-func runTunnel(ctx context.Context, out io.Writer, codespaceName string, deps tunnelDeps) error {
+// forwardOptions are the ssh options that forward the remote port to
+// socketPath. ssh only reports the port it was allocated at the default log
+// level, so that is forced for dynamicPort.
+func forwardOptions(socketPath, port string) []string {
+	options := []string{"-R", fmt.Sprintf("localhost:%s:%s", port, socketPath)}
+	if port == dynamicPort {
+		options = append([]string{"-o", "LogLevel=INFO"}, options...)
+	}
+	return options
+}
+
+// sessionCheckInterval is how often a tunnel checks the local server and
+// whether gh rdm tunnel stop removed its session. Tests shorten it.
+var sessionCheckInterval = 5 * time.Second
+
+// runTunnel connects to the chosen codespaces, one supervised tunnel each,
+// and returns once they have all ended.
+func runTunnel(ctx context.Context, out io.Writer, opts tunnelOptions, deps tunnelDeps) error {
 	socketPath := deps.socketPath()
 	if err := ensureLocalServer(ctx, out, socketPath, deps); err != nil {
 		return err
 	}
 
-	resolvedCodespace, err := resolveCodespace(ctx, codespaceName, deps.listCodespaces)
-	if err != nil {
+	var targets []tunnelTarget
+	if opts.sshHost != "" {
+		targets = []tunnelTarget{{name: opts.sshHost, ssh: true, sshArgs: opts.sshArgs}}
+	} else {
+		codespaces, err := resolveCodespaces(ctx, opts, deps.listCodespaces, deps.pickCodespace)
+		if err != nil {
+			return err
+		}
+		for _, name := range codespaces {
+			targets = append(targets, codespaceTarget(name))
+		}
+	}
+	if len(targets) == 1 {
+		return superviseTunnel(ctx, out, targets[0], socketPath, opts.once, deps)
+	}
+
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.name)
+	}
+	fmt.Fprintf(out, "Starting tunnels to %d codespaces: %s\n", len(targets), strings.Join(names, ", "))
+	var mu sync.Mutex
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := &prefixWriter{mu: &mu, w: out, prefix: "[" + target.name + "] "}
+			if err := superviseTunnel(ctx, w, target, socketPath, opts.once, deps); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", target.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// superviseTunnel keeps a tunnel to target up, reconnecting with
+// backoff whenever it drops, until ctx is cancelled, the session is stopped,
+// or, with once, the first connection ends. The tunnel is registered in the
+// session registry while it runs.
+func superviseTunnel(ctx context.Context, out io.Writer, target tunnelTarget, socketPath string, once bool, deps tunnelDeps) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session := &sessionRecorder{
+		session: tunnelSession{Target: target.name, PID: os.Getpid(), Started: deps.now(), State: sessionConnecting},
+		save:    deps.saveSession,
+		out:     out,
+	}
+	session.update(func(*tunnelSession) {})
+	defer deps.removeSession(target.name, session.pid())
+
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		monitorTunnel(ctx, cancel, out, session, socketPath, deps)
+	}()
+	defer func() {
+		cancel()
+		<-monitorDone
+	}()
+
+	forwarding := func(port string) {
+		session.update(func(s *tunnelSession) {
+			s.State = sessionForwarding
+			s.Port = port
+		})
+	}
+
+	if once {
+		err := connectTunnel(ctx, out, target, socketPath, forwarding, deps)
+		if ctx.Err() != nil {
+			return nil
+		}
 		return err
 	}
 
-	fmt.Fprintf(out, "Starting tunnel to codespace %q\n", resolvedCodespace)
-	fmt.Fprintf(out, "Forwarding localhost:%s to %s\n", rdmTunnelPort, socketPath)
+	backoff := tunnelMinBackoff
+	for attempt := 1; ; attempt++ {
+		started := deps.now()
+		var err error
+		if attempt > 1 {
+			err = ensureLocalServer(ctx, out, socketPath, deps)
+		}
+		if err == nil {
+			err = connectTunnel(ctx, out, target, socketPath, forwarding, deps)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if deps.now().Sub(started) >= tunnelStableAfter {
+			backoff = tunnelMinBackoff
+		}
+
+		reason := "connection closed"
+		if err != nil {
+			reason = err.Error()
+		}
+		session.update(func(s *tunnelSession) {
+			s.State = sessionReconnecting
+			s.LastError = reason
+			s.Reconnects++
+		})
+		fmt.Fprintf(out, "%s ⚠️  Tunnel to %s dropped: %s\n", deps.now().Format("15:04:05"), target, reason)
+		fmt.Fprintf(out, "Reconnecting in %s (attempt %d)...\n", backoff, attempt+1)
+		if err := deps.sleep(ctx, backoff); err != nil {
+			return nil
+		}
+		backoff = min(backoff*2, tunnelMaxBackoff)
+	}
+}
+
+// monitorTunnel checks the local server every sessionCheckInterval and
+// records the result, and cancels the tunnel when its session is removed.
+func monitorTunnel(ctx context.Context, cancel context.CancelFunc, out io.Writer, session *sessionRecorder, socketPath string, deps tunnelDeps) {
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A session that was never saved can't have been stopped.
+		if session.wasSaved() && !deps.sessionExists(session.target(), session.pid()) {
+			fmt.Fprintf(out, "Tunnel to %s stopped\n", session.target())
+			cancel()
+			return
+		}
+		err := deps.statusUnix(ctx, socketPath)
+		session.update(func(s *tunnelSession) {
+			s.LastCheck = deps.now()
+			s.Healthy = err == nil && s.State == sessionForwarding
+			if err != nil {
+				s.LastError = fmt.Sprintf("local server: %v", err)
+			}
+		})
+	}
+}
+
+// sessionRecorder serializes updates to a tunnel's registry entry.
+type sessionRecorder struct {
+	mu      sync.Mutex
+	session tunnelSession
+	save    func(tunnelSession) error
+	out     io.Writer
+	warned  bool
+	saved   bool
+}
+
+func (r *sessionRecorder) target() string {
+	return r.session.Target
+}
+
+func (r *sessionRecorder) pid() int {
+	return r.session.PID
+}
+
+// wasSaved reports whether the session has been written to the registry.
+func (r *sessionRecorder) wasSaved() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.saved
+}
+
+// update applies fn to the session and saves it. A failed save is reported
+// once; the tunnel itself keeps working.
+func (r *sessionRecorder) update(fn func(*tunnelSession)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.session)
+	err := r.save(r.session)
+	if err == nil {
+		r.saved = true
+	} else if !r.warned {
+		fmt.Fprintf(r.out, "Warning: could not record tunnel session: %v\n", err)
+		r.warned = true
+	}
+}
+
+// prefixWriter starts every line with prefix so several tunnels can share
+// one terminal. mu serializes writes between them.
+type prefixWriter struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if !p.midLine {
+			buf.WriteString(p.prefix)
+		}
+		buf.Write(line)
+		p.midLine = line[len(line)-1] != '\n'
+	}
+	if _, err := p.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// connectTunnel hands the session token and a fixed remote port to the target
+// and forwards the socket until the connection ends, calling forwarding with
+// the port once the forward is up. A port the remote sshd allocated is only
+// recorded on the target then, so remote clients never look for a forward
+// that isn't there. The token is sent every time because it changes when the
+// local server restarts.
+func connectTunnel(ctx context.Context, out io.Writer, target tunnelTarget, socketPath string, forwarding func(string), deps tunnelDeps) error {
+	token := deps.readToken()
+	if token == "" {
+		return errors.New("local server did not publish a session token; restart it with `gh rdm stop && gh rdm server`")
+	}
+	target.port = deps.remotePort()
+	fmt.Fprintf(out, "Sending session token to %s\n", target)
+	if err := deps.sendToken(ctx, target, token); err != nil {
+		return fmt.Errorf("send session token: %w", err)
+	}
+
+	fmt.Fprintf(out, "Starting tunnel to %s\n", target)
 	fmt.Fprintln(out, "Press Ctrl-C to stop the tunnel.")
 
-	return deps.runTunnel(ctx, resolvedCodespace, socketPath)
+	return deps.runTunnel(ctx, target, socketPath, func(port string) {
+		fmt.Fprintf(out, "Forwarding localhost:%s to %s\n", port, socketPath)
+		forwarding(port)
+	})
 }
 
+// sleepContext waits for d, or returns ctx's error if it is cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// localServerMu keeps tunnels that reconnect at the same time from each
+// starting a server.
+var localServerMu sync.Mutex
+
 func ensureLocalServer(ctx context.Context, out io.Writer, socketPath string, deps tunnelDeps) error {
+	localServerMu.Lock()
+	defer localServerMu.Unlock()
+
 	if err := deps.statusUnix(ctx, socketPath); err == nil {
 		fmt.Fprintf(out, "✓ Local server is running at %s\n", socketPath)
 		return nil
@@ -133,7 +606,47 @@ func ensureLocalServer(ctx context.Context, out io.Writer, socketPath string, de
 	return fmt.Errorf("local server did not become ready: %w", lastErr)
 }
 
-func resolveCodespace(ctx context.Context, requested string, listCodespaces func(context.Context) ([]codespace, error)) (string, error) {
+// resolveCodespaces returns the codespaces opts asks for: the named ones,
+// every available one with all, or a single one from resolveCodespace.
+func resolveCodespaces(ctx context.Context, opts tunnelOptions, listCodespaces func(context.Context) ([]codespace, error), pick func(context.Context, []codespace) (string, error)) ([]string, error) {
+	if opts.all {
+		codespaces, err := listCodespaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, codespace := range codespaces {
+			if codespace.State == "Available" {
+				names = append(names, codespace.Name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, errors.New("no available codespaces; start one or pass its name")
+		}
+		return names, nil
+	}
+
+	var names []string
+	for _, name := range opts.codespaces {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		return names, nil
+	}
+
+	name, err := resolveCodespace(ctx, "", listCodespaces, pick)
+	if err != nil {
+		return nil, err
+	}
+	return []string{name}, nil
+}
+
+// resolveCodespace returns requested, or the only codespace there is. With
+// several, it lets the user pick one when pick is set and there is a
+// terminal, and otherwise lists them in the error.
+func resolveCodespace(ctx context.Context, requested string, listCodespaces func(context.Context) ([]codespace, error), pick func(context.Context, []codespace) (string, error)) (string, error) {
 	if requested != "" {
 		return requested, nil
 	}
@@ -148,6 +661,12 @@ func resolveCodespace(ctx context.Context, requested string, listCodespaces func
 	if len(codespaces) == 1 {
 		return codespaces[0].Name, nil
 	}
+	if pick != nil {
+		name, err := pick(ctx, codespaces)
+		if !errors.Is(err, errNoTerminal) {
+			return name, err
+		}
+	}
 
 	var names []string
 	for _, codespace := range codespaces {
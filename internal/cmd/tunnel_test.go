@@ -4,8 +4,17 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
 )
 
 func TestRunTunnelUsesRequestedCodespaceAndExistingServer(t *testing.T) {
@@ -17,13 +26,13 @@ func TestRunTunnelUsesRequestedCodespaceAndExistingServer(t *testing.T) {
 		startedServer = true
 		return nil
 	}
-	deps.runTunnel = func(_ context.Context, codespaceName, socketPath string) error {
-		ranCodespace = codespaceName
+	deps.runTunnel = func(_ context.Context, target tunnelTarget, socketPath string, _ func(string)) error {
+		ranCodespace = target.name
 		ranSocket = socketPath
 		return nil
 	}
 
-	err := runTunnel(context.Background(), &out, "my-space", deps)
+	err := runTunnel(context.Background(), &out, tunnelOptions{codespaces: []string{"my-space"}, once: true}, deps)
 	if err != nil {
 		t.Fatalf("runTunnel() error = %v, want nil", err)
 	}
@@ -58,7 +67,7 @@ func TestRunTunnelStartsServerAndSelectsOnlyCodespace(t *testing.T) {
 		return []codespace{{Name: "only-space", State: "Available"}}, nil
 	}
 
-	err := runTunnel(context.Background(), &out, "", deps)
+	err := runTunnel(context.Background(), &out, tunnelOptions{once: true}, deps)
 	if err != nil {
 		t.Fatalf("runTunnel() error = %v, want nil", err)
 	}
@@ -77,12 +86,578 @@ func TestRunTunnelStartsServerAndSelectsOnlyCodespace(t *testing.T) {
 	}
 }
 
+func TestRunTunnelSendsTokenBeforeForwarding(t *testing.T) {
+	var out bytes.Buffer
+	var steps []string
+	deps := fakeTunnelDeps()
+	deps.sendToken = func(_ context.Context, target tunnelTarget, token string) error {
+		steps = append(steps, "token "+target.name+" "+token)
+		return nil
+	}
+	deps.runTunnel = func(_ context.Context, target tunnelTarget, _ string, _ func(string)) error {
+		steps = append(steps, "tunnel "+target.name)
+		return nil
+	}
+
+	if err := runTunnel(context.Background(), &out, tunnelOptions{codespaces: []string{"my-space"}, once: true}, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v, want nil", err)
+	}
+
+	want := []string{"token my-space session-token", "tunnel my-space"}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("runTunnel() steps = %q, want %q", steps, want)
+	}
+}
+
+func TestRunTunnelFailsWithoutToken(t *testing.T) {
+	var out bytes.Buffer
+	ranTunnel := false
+	deps := fakeTunnelDeps()
+	deps.readToken = func() string {
+		return ""
+	}
+	deps.runTunnel = func(context.Context, tunnelTarget, string, func(string)) error {
+		ranTunnel = true
+		return nil
+	}
+
+	err := runTunnel(context.Background(), &out, tunnelOptions{codespaces: []string{"my-space"}, once: true}, deps)
+	if err == nil {
+		t.Fatal("runTunnel() error = nil, want error")
+	}
+	if ranTunnel {
+		t.Fatal("runTunnel() started forwarding without a token")
+	}
+}
+
+func TestRunTunnelReconnectsWithBackoff(t *testing.T) {
+	var out bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var sleeps []time.Duration
+	statusChecks, tokensSent, attempts := 0, 0, 0
+	deps := fakeTunnelDeps()
+	deps.statusUnix = func(context.Context, string) error {
+		statusChecks++
+		return nil
+	}
+	deps.sendToken = func(context.Context, tunnelTarget, string) error {
+		tokensSent++
+		return nil
+	}
+	deps.runTunnel = func(context.Context, tunnelTarget, string, func(string)) error {
+		attempts++
+		if attempts == 4 {
+			cancel()
+			return errors.New("signal: interrupt")
+		}
+		return errors.New("exit status 255")
+	}
+	deps.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	if err := runTunnel(ctx, &out, tunnelOptions{codespaces: []string{"my-space"}}, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v, want nil after cancel", err)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if len(sleeps) != len(want) || sleeps[0] != want[0] || sleeps[1] != want[1] || sleeps[2] != want[2] {
+		t.Fatalf("backoff = %v, want %v", sleeps, want)
+	}
+	if statusChecks != 4 || tokensSent != 4 {
+		t.Fatalf("status checks = %d, tokens sent = %d, want 4 each", statusChecks, tokensSent)
+	}
+	if !strings.Contains(out.String(), "dropped: exit status 255") {
+		t.Fatalf("runTunnel() output missing reconnect reason:\n%s", out.String())
+	}
+}
+
+func TestRunTunnelResetsBackoffAfterStableConnection(t *testing.T) {
+	var out bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	attempts := 0
+	deps := fakeTunnelDeps()
+	deps.now = func() time.Time { return clock }
+	deps.runTunnel = func(context.Context, tunnelTarget, string, func(string)) error {
+		attempts++
+		switch attempts {
+		case 3:
+			// Stayed up for an hour before dropping.
+			clock = clock.Add(time.Hour)
+		case 4:
+			cancel()
+		}
+		return errors.New("exit status 255")
+	}
+	deps.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	runTunnel(ctx, &out, tunnelOptions{codespaces: []string{"my-space"}}, deps)
+
+	want := []time.Duration{time.Second, 2 * time.Second, time.Second}
+	if len(sleeps) != len(want) || sleeps[0] != want[0] || sleeps[1] != want[1] || sleeps[2] != want[2] {
+		t.Fatalf("backoff = %v, want %v", sleeps, want)
+	}
+}
+
+func TestRunTunnelOnceReturnsConnectionError(t *testing.T) {
+	var out bytes.Buffer
+	deps := fakeTunnelDeps()
+	deps.runTunnel = func(context.Context, tunnelTarget, string, func(string)) error {
+		return errors.New("exit status 255")
+	}
+	deps.sleep = func(context.Context, time.Duration) error {
+		t.Fatal("runTunnel() retried with --once")
+		return nil
+	}
+
+	if err := runTunnel(context.Background(), &out, tunnelOptions{codespaces: []string{"my-space"}, once: true}, deps); err == nil {
+		t.Fatal("runTunnel() error = nil, want the connection error")
+	}
+}
+
+func TestRunTunnelServesSeveralCodespaces(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	var ran []string
+	deps := fakeTunnelDeps()
+	deps.listCodespaces = func(context.Context) ([]codespace, error) {
+		return []codespace{
+			{Name: "alpha", State: "Available"},
+			{Name: "beta", State: "Shutdown"},
+			{Name: "gamma", State: "Available"},
+		}, nil
+	}
+	deps.runTunnel = func(_ context.Context, target tunnelTarget, _ string, forwarding func(string)) error {
+		forwarding(target.port)
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, target.name)
+		return nil
+	}
+
+	if err := runTunnel(context.Background(), &out, tunnelOptions{all: true, once: true}, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v", err)
+	}
+
+	slices.Sort(ran)
+	if want := []string{"alpha", "gamma"}; !slices.Equal(ran, want) {
+		t.Fatalf("runTunnel() connected to %v, want %v", ran, want)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if strings.Contains(line, "Forwarding") && !strings.HasPrefix(line, "[alpha] ") && !strings.HasPrefix(line, "[gamma] ") {
+			t.Fatalf("tunnel output line not prefixed with its codespace: %q", line)
+		}
+	}
+}
+
+func TestRunTunnelStopsWhenSessionIsRemoved(t *testing.T) {
+	defer func(d time.Duration) { sessionCheckInterval = d }(sessionCheckInterval)
+	sessionCheckInterval = time.Millisecond
+
+	var out bytes.Buffer
+	var mu sync.Mutex
+	var states []string
+	removed := false
+	deps := fakeTunnelDeps()
+	deps.saveSession = func(s tunnelSession) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(states) == 0 || states[len(states)-1] != s.State {
+			states = append(states, s.State)
+		}
+		return nil
+	}
+	deps.sessionExists = func(string, int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		// Stop once the tunnel is up.
+		return !slices.Contains(states, sessionForwarding)
+	}
+	deps.removeSession = func(string, int) error {
+		removed = true
+		return nil
+	}
+	deps.runTunnel = func(ctx context.Context, target tunnelTarget, _ string, forwarding func(string)) error {
+		forwarding(target.port)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if err := runTunnel(context.Background(), &out, tunnelOptions{codespaces: []string{"my-space"}}, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v, want nil after stop", err)
+	}
+	if want := []string{sessionConnecting, sessionForwarding}; !slices.Equal(states[:2], want) {
+		t.Fatalf("session states = %v, want %v first", states, want)
+	}
+	if !removed {
+		t.Fatal("runTunnel() did not remove its session")
+	}
+	if !strings.Contains(out.String(), "stopped") {
+		t.Fatalf("runTunnel() output missing stop message:\n%s", out.String())
+	}
+}
+
+func TestRunTunnelKeepsRunningWhenSessionCannotBeSaved(t *testing.T) {
+	defer func(d time.Duration) { sessionCheckInterval = d }(sessionCheckInterval)
+	sessionCheckInterval = time.Millisecond
+
+	var out bytes.Buffer
+	deps := fakeTunnelDeps()
+	deps.saveSession = func(tunnelSession) error { return errors.New("read-only file system") }
+	deps.sessionExists = func(string, int) bool { return false }
+	deps.runTunnel = func(ctx context.Context, _ tunnelTarget, _ string, _ func(string)) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return errors.New("tunnel closed")
+		}
+	}
+
+	err := runTunnel(context.Background(), &out, tunnelOptions{codespaces: []string{"my-space"}, once: true}, deps)
+	if err == nil || !strings.Contains(err.Error(), "tunnel closed") {
+		t.Fatalf("runTunnel() error = %v, want the tunnel's own error", err)
+	}
+	if strings.Contains(out.String(), "stopped") {
+		t.Fatalf("runTunnel() stopped an unsaved session:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "could not record tunnel session") {
+		t.Fatalf("runTunnel() output missing save warning:\n%s", out.String())
+	}
+}
+
+func TestRunTunnelToSSHHost(t *testing.T) {
+	var out bytes.Buffer
+	var tokenTarget, tunnelTargetSeen tunnelTarget
+	listed := false
+	deps := fakeTunnelDeps()
+	deps.listCodespaces = func(context.Context) ([]codespace, error) {
+		listed = true
+		return nil, nil
+	}
+	deps.sendToken = func(_ context.Context, target tunnelTarget, _ string) error {
+		tokenTarget = target
+		return nil
+	}
+	deps.runTunnel = func(_ context.Context, target tunnelTarget, _ string, _ func(string)) error {
+		tunnelTargetSeen = target
+		return nil
+	}
+
+	opts := tunnelOptions{sshHost: "devvm", sshArgs: []string{"-J", "bastion"}, once: true}
+	if err := runTunnel(context.Background(), &out, opts, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v", err)
+	}
+	if listed {
+		t.Fatal("runTunnel() listed codespaces for an SSH host")
+	}
+	want := tunnelTarget{name: "devvm", ssh: true, sshArgs: []string{"-J", "bastion"}, port: "7391"}
+	if !reflect.DeepEqual(tokenTarget, want) || !reflect.DeepEqual(tunnelTargetSeen, want) {
+		t.Fatalf("runTunnel() targets = %+v, %+v, want %+v", tokenTarget, tunnelTargetSeen, want)
+	}
+	if !strings.Contains(out.String(), `Starting tunnel to host "devvm"`) {
+		t.Fatalf("runTunnel() output missing host:\n%s", out.String())
+	}
+}
+
+func TestTunnelCommandLeavesStdinAlone(t *testing.T) {
+	target := tunnelTarget{name: "devbox", port: "7391", ssh: true}
+	cmd, err := tunnelCommand(context.Background(), target, "/tmp/rdm.sock", func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Stdin != nil {
+		t.Fatalf("tunnelCommand() stdin = %v, want none so concurrent tunnels don't read the terminal", cmd.Stdin)
+	}
+	if got := strings.Join(cmd.Args, " "); !strings.Contains(got, "-N -R localhost:7391:/tmp/rdm.sock devbox") {
+		t.Fatalf("tunnelCommand() args = %q", got)
+	}
+}
+
+func TestTunnelCommandRecordsAllocatedPortOverItsConnection(t *testing.T) {
+	target := tunnelTarget{name: "devbox", port: dynamicPort, ssh: true}
+	cmd, err := tunnelCommand(context.Background(), target, "/tmp/rdm.sock", func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Stdin == nil || cmd.Stdin == os.Stdin {
+		t.Fatalf("tunnelCommand() stdin = %v, want a pipe to hand over the port", cmd.Stdin)
+	}
+	args := strings.Join(cmd.Args, " ")
+	if strings.Contains(args, "-N") || !strings.HasSuffix(args, "devbox "+remotePortRecorder) {
+		t.Fatalf("tunnelCommand() args = %q, want the port recorder as the remote command", args)
+	}
+}
+
+func TestTunnelCommandWritesAllocatedPortToRemote(t *testing.T) {
+	home := t.TempDir()
+	bin := t.TempDir()
+	// A stand-in ssh that reports an allocated port and runs the remote
+	// command locally.
+	fakeSSH := "#!/bin/sh\necho 'Allocated port 41237 for remote forward to /tmp/rdm.sock' >&2\nfor last; do :; done\nexec sh -c \"$last\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "ssh"), []byte(fakeSSH), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", home)
+	t.Setenv(client.PortEnv, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd, err := tunnelCommand(ctx, tunnelTarget{name: "devbox", port: dynamicPort, ssh: true}, "/tmp/rdm.sock", func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cancel()
+		cmd.Wait()
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); client.Port() != "41237"; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("remote port = %q, want the allocated 41237 recorded over the tunnel", client.Port())
+		}
+	}
+}
+
+func TestTunnelTargetCommand(t *testing.T) {
+	options := []string{"-N", "-R", "localhost:7391:/tmp/rdm.sock"}
+
+	codespace := codespaceTarget("my-space").command(options, "")
+	if want := "gh cs ssh -c my-space -- -N -R localhost:7391:/tmp/rdm.sock"; strings.Join(codespace, " ") != want {
+		t.Fatalf("codespace command = %q, want %q", codespace, want)
+	}
+
+	host := tunnelTarget{name: "devvm", ssh: true, sshArgs: []string{"-p", "2222"}}
+	if got, want := strings.Join(host.command(options, ""), " "), "ssh -p 2222 -N -R localhost:7391:/tmp/rdm.sock devvm"; got != want {
+		t.Fatalf("ssh command = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(host.command(nil, "cat > token"), " "), "ssh -p 2222 devvm cat > token"; got != want {
+		t.Fatalf("ssh token command = %q, want %q", got, want)
+	}
+}
+
+func TestRunTunnelPicksPortPerConnection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ports := []string{"24001", "24002"}
+	var sent, forwarded []string
+	deps := fakeTunnelDeps()
+	deps.remotePort = func() string {
+		port := ports[0]
+		ports = ports[1:]
+		return port
+	}
+	deps.sendToken = func(_ context.Context, target tunnelTarget, _ string) error {
+		sent = append(sent, target.port)
+		return nil
+	}
+	deps.runTunnel = func(_ context.Context, target tunnelTarget, _ string, _ func(string)) error {
+		forwarded = append(forwarded, target.port)
+		if len(forwarded) == 2 {
+			cancel()
+		}
+		return errors.New("remote port forwarding failed")
+	}
+
+	if err := runTunnel(ctx, &bytes.Buffer{}, tunnelOptions{codespaces: []string{"my-space"}}, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v", err)
+	}
+	want := []string{"24001", "24002"}
+	if !slices.Equal(sent, want) || !slices.Equal(forwarded, want) {
+		t.Fatalf("runTunnel() sent ports %q and forwarded %q, want %q for both", sent, forwarded, want)
+	}
+}
+
+func TestRunTunnelLeavesAllocatedPortToItsConnection(t *testing.T) {
+	var out bytes.Buffer
+	var steps []string
+	var saved tunnelSession
+	deps := fakeTunnelDeps()
+	deps.remotePort = func() string { return dynamicPort }
+	deps.sendToken = func(_ context.Context, target tunnelTarget, _ string) error {
+		steps = append(steps, "token "+target.port)
+		return nil
+	}
+	deps.recordPort = func(_ context.Context, _ tunnelTarget, port string) error {
+		steps = append(steps, "record "+port)
+		return nil
+	}
+	deps.runTunnel = func(_ context.Context, target tunnelTarget, _ string, forwarding func(string)) error {
+		steps = append(steps, "forward "+target.port)
+		forwarding("41235")
+		return nil
+	}
+	deps.saveSession = func(s tunnelSession) error {
+		saved = s
+		return nil
+	}
+
+	if err := runTunnel(context.Background(), &out, tunnelOptions{codespaces: []string{"my-space"}, once: true}, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v", err)
+	}
+	// runTunnel records the port over its own connection, so no second
+	// one is opened.
+	if want := []string{"token 0", "forward 0"}; !slices.Equal(steps, want) {
+		t.Fatalf("runTunnel() steps = %q, want %q", steps, want)
+	}
+	if saved.Port != "41235" {
+		t.Fatalf("session port = %q, want the allocated 41235", saved.Port)
+	}
+	if !strings.Contains(out.String(), "Forwarding localhost:41235 to /tmp/gh-rdm.sock") {
+		t.Fatalf("runTunnel() output missing allocated port:\n%s", out.String())
+	}
+}
+
+func TestRunTunnelKeepsConfiguredPortUnrecorded(t *testing.T) {
+	recorded := false
+	deps := fakeTunnelDeps()
+	deps.remotePort = func() string { return "8123" }
+	deps.recordPort = func(context.Context, tunnelTarget, string) error {
+		recorded = true
+		return nil
+	}
+
+	if err := runTunnel(context.Background(), &bytes.Buffer{}, tunnelOptions{codespaces: []string{"my-space"}, once: true}, deps); err != nil {
+		t.Fatalf("runTunnel() error = %v", err)
+	}
+	if recorded {
+		t.Fatal("runTunnel() recorded a configured port after forwarding; the token handoff does that")
+	}
+}
+
+func TestRemotePort(t *testing.T) {
+	t.Setenv(client.PortEnv, "8123")
+	if got := remotePort(); got != "8123" {
+		t.Fatalf("remotePort() = %q, want the configured port", got)
+	}
+
+	t.Setenv(client.PortEnv, "")
+	if got := remotePort(); got != dynamicPort {
+		t.Fatalf("remotePort() = %q, want %q for the remote sshd to pick", got, dynamicPort)
+	}
+}
+
+func TestForwardOptions(t *testing.T) {
+	if got, want := strings.Join(forwardOptions("/tmp/rdm.sock", "8123"), " "), "-R localhost:8123:/tmp/rdm.sock"; got != want {
+		t.Fatalf("forwardOptions() = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(forwardOptions("/tmp/rdm.sock", dynamicPort), " "), "-o LogLevel=INFO -R localhost:0:/tmp/rdm.sock"; got != want {
+		t.Fatalf("forwardOptions() = %q, want %q", got, want)
+	}
+}
+
+func TestRemoteTokenScriptRecordsPort(t *testing.T) {
+	home := t.TempDir()
+	runRemoteScript(t, home, remoteTokenScript("24817"), "stale-token\n")
+	runRemoteScript(t, home, remoteTokenScript("7391"), "session-token\n")
+
+	t.Setenv("HOME", home)
+	t.Setenv(client.PortEnv, "")
+	t.Setenv(client.TokenEnv, "")
+	portPath, err := client.PortPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(portPath); err != nil || strings.TrimSpace(string(data)) != "7391" {
+		t.Fatalf("port file = %q (%v) after the handoff, want 7391", data, err)
+	}
+	if got := client.ReadToken(""); got != "session-token" {
+		t.Fatalf("client.ReadToken() = %q after the handoff, want session-token", got)
+	}
+}
+
+func TestRemoteTokenScriptKeepsOtherPortsApart(t *testing.T) {
+	home := t.TempDir()
+	runRemoteScript(t, home, remoteTokenScript("7391"), "default-token\n")
+	runRemoteScript(t, home, remoteTokenScript("8123"), "work-token\n")
+
+	t.Setenv("HOME", home)
+	t.Setenv(client.PortEnv, "")
+	t.Setenv(client.TokenEnv, "")
+	if got := client.Port(); got != "7391" {
+		t.Fatalf("client.Port() = %q, want the default setup's 7391", got)
+	}
+	if got := client.ReadToken(""); got != "default-token" {
+		t.Fatalf("default token = %q, want default-token", got)
+	}
+	if got := client.ReadToken(client.PortTokenKey("8123")); got != "work-token" {
+		t.Fatalf("token for port 8123 = %q, want work-token", got)
+	}
+}
+
+func TestRemoteTokenScriptLeavesDynamicPortForLater(t *testing.T) {
+	home := t.TempDir()
+	runRemoteScript(t, home, remoteTokenScript(dynamicPort), "session-token\n")
+
+	t.Setenv("HOME", home)
+	t.Setenv(client.PortEnv, "")
+	t.Setenv(client.TokenEnv, "")
+	portPath, err := client.PortPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(portPath); !os.IsNotExist(err) {
+		t.Fatalf("token handoff wrote the port file before the forward was up: %v", err)
+	}
+
+	runRemoteScript(t, home, remotePortScript("41235"), "")
+	if got := client.Port(); got != "41235" {
+		t.Fatalf("client.Port() = %q after recording, want 41235", got)
+	}
+
+	// The tunnel's recorder reads the port, then holds on until stdin ends.
+	runRemoteScript(t, home, remotePortRecorder, "41236\n")
+	if got := client.Port(); got != "41236" {
+		t.Fatalf("client.Port() = %q after the tunnel recorded its port, want 41236", got)
+	}
+}
+
+// runRemoteScript runs script as the remote side would, with home as $HOME
+// and stdin on standard input.
+func runRemoteScript(t *testing.T, home, script, stdin string) {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Env = append(os.Environ(), "HOME="+home)
+	cmd.Stdin = strings.NewReader(stdin)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %v: %s", script, err, output)
+	}
+}
+
+func TestResolveCodespacesAllRequiresAvailable(t *testing.T) {
+	_, err := resolveCodespaces(context.Background(), tunnelOptions{all: true}, func(context.Context) ([]codespace, error) {
+		return []codespace{{Name: "asleep", State: "Shutdown"}}, nil
+	}, nil)
+	if err == nil {
+		t.Fatal("resolveCodespaces() error = nil, want error")
+	}
+}
+
 func TestResolveCodespaceRequiresExplicitNameWhenMultipleExist(t *testing.T) {
 	_, err := resolveCodespace(context.Background(), "", func(context.Context) ([]codespace, error) {
 		return []codespace{
 			{Name: "first", State: "Available"},
 			{Name: "second", State: "Shutdown"},
 		}, nil
+	}, func(context.Context, []codespace) (string, error) {
+		return "", errNoTerminal
 	})
 	if err == nil {
 		t.Fatal("resolveCodespace() error = nil, want error")
@@ -106,8 +681,53 @@ func fakeTunnelDeps() tunnelDeps {
 		listCodespaces: func(context.Context) ([]codespace, error) {
 			return nil, nil
 		},
-		runTunnel: func(context.Context, string, string) error {
+		readToken: func() string {
+			return "session-token"
+		},
+		remotePort: func() string {
+			return "7391"
+		},
+		sendToken: func(context.Context, tunnelTarget, string) error {
+			return nil
+		},
+		recordPort: func(context.Context, tunnelTarget, string) error {
+			return nil
+		},
+		runTunnel: func(_ context.Context, target tunnelTarget, _ string, forwarding func(string)) error {
+			forwarding(target.port)
 			return nil
 		},
+		runSession: func(context.Context, tunnelTarget, []string, func(string)) error {
+			return nil
+		},
+		now: time.Now,
+		sleep: func(context.Context, time.Duration) error {
+			return nil
+		},
+		saveSession: func(tunnelSession) error {
+			return nil
+		},
+		sessionExists: func(string, int) bool {
+			return true
+		},
+		removeSession: func(string, int) error {
+			return nil
+		},
+	}
+}
+
+func TestResolveCodespacePicksInteractively(t *testing.T) {
+	var offered []codespace
+	name, err := resolveCodespace(context.Background(), "", func(context.Context) ([]codespace, error) {
+		return []codespace{{Name: "first"}, {Name: "second"}}, nil
+	}, func(_ context.Context, codespaces []codespace) (string, error) {
+		offered = codespaces
+		return "second", nil
+	})
+	if err != nil {
+		t.Fatalf("resolveCodespace() error = %v", err)
+	}
+	if name != "second" || len(offered) != 2 {
+		t.Fatalf("resolveCodespace() = %q after offering %v, want the picked codespace", name, offered)
 	}
 }
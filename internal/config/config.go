@@ -0,0 +1,127 @@
+// Package config reads the gh-rdm configuration file.
+//
+// The file is YAML. Commands are either a list or a string split like a
+// shell would, and lists of one may be written as a plain value.
+//
+//	port: 7392
+//	commands:
+//	  copy: copyq add -
+//	  open: [firefox, -P, work, --new-tab]
+//	  screenshot-dir: ~/Pictures/Shots
+//	open:
+//	  schemes: [http, https]
+//	  action: prompt
+//	images:
+//	  max-width: 1600
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Config is the contents of the configuration file.
+type Config struct {
+	// Port is the remote port the tunnel forwards. Zero keeps the default.
+	Port int `yaml:"port"`
+	// Socket is where the local server listens. Empty keeps the default.
+	Socket   string   `yaml:"socket"`
+	Commands Commands `yaml:"commands"`
+	Open     Open     `yaml:"open"`
+	Images   Images   `yaml:"images"`
+}
+
+// Commands overrides the host commands the server runs. Empty argvs keep
+// the built-in behaviour.
+type Commands struct {
+	// Copy reads the text to copy on stdin, and Paste writes the clipboard
+	// to stdout.
+	Copy  Argv `yaml:"copy"`
+	Paste Argv `yaml:"paste"`
+	// Open is run with the URL or path appended.
+	Open Argv `yaml:"open"`
+	// ClipboardImage writes the clipboard image as PNG to stdout.
+	ClipboardImage Argv `yaml:"clipboard-image"`
+	// ScreenshotDir is where gh rdm screenshot looks for screenshots.
+	ScreenshotDir string `yaml:"screenshot-dir"`
+}
+
+// Open is the policy for remote open requests. Empty fields keep the
+// server's defaults.
+type Open struct {
+	// Schemes are the URL schemes opened without asking.
+	Schemes List `yaml:"schemes"`
+	// Hosts limits those URLs to hosts matching one of these patterns.
+	Hosts List `yaml:"hosts"`
+	// Action is "deny" or "prompt", for everything else.
+	Action string `yaml:"action"`
+}
+
+// Images are the server's defaults for converting screenshots and clipboard
+// images. Clients can override them per request.
+type Images struct {
+	// MaxWidth scales wider images down. Zero means no limit.
+	MaxWidth int `yaml:"max-width"`
+	// Format is "jpeg" or "png". Empty keeps the source format.
+	Format string `yaml:"format"`
+	// Quality is the JPEG quality. Zero means the default.
+	Quality int `yaml:"quality"`
+}
+
+// Path returns the configuration file location:
+// $XDG_CONFIG_HOME/gh-rdm/config.yml, or ~/.config/gh-rdm/config.yml.
+func Path() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh-rdm", "config.yml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(home, ".config", "gh-rdm", "config.yml"), nil
+}
+
+// Load reads the configuration file. A missing file is an empty Config.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads the configuration from path. A missing file is an empty
+// Config.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes a configuration file. Errors start with the offending line
+// number, and unknown settings are errors.
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, yamlError(err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`# gh-rdm settings
+commands:
+  copy: copyq add -   # stdin is the text
+  paste: [copyq, read, "0"]
+  open:
+    - firefox
+    - -P
+    - 'work # profile'
+  clipboard-image: xclip -selection clipboard -t image/png -o
+  screenshot-dir: "~/Pictures/Shots"
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := Commands{
+		Copy:           []string{"copyq", "add", "-"},
+		Paste:          []string{"copyq", "read", "0"},
+		Open:           []string{"firefox", "-P", "work # profile"},
+		ClipboardImage: []string{"xclip", "-selection", "clipboard", "-t", "image/png", "-o"},
+		ScreenshotDir:  "~/Pictures/Shots",
+	}
+	if !reflect.DeepEqual(cfg.Commands, want) {
+		t.Fatalf("Parse() = %#v, want %#v", cfg.Commands, want)
+	}
+}
+
+func TestParseOpenPolicy(t *testing.T) {
+	cfg, err := Parse([]byte("open:\n  schemes: [https, zoommtg]\n  hosts: '*.github.com'\n  action: prompt\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := Open{Schemes: []string{"https", "zoommtg"}, Hosts: []string{"*.github.com"}, Action: "prompt"}
+	if !reflect.DeepEqual(cfg.Open, want) {
+		t.Fatalf("Parse() = %#v, want %#v", cfg.Open, want)
+	}
+}
+
+func TestParseImages(t *testing.T) {
+	cfg, err := Parse([]byte("images:\n  max-width: 1600\n  format: jpeg\n  quality: 80\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := (Images{MaxWidth: 1600, Format: "jpeg", Quality: 80}); cfg.Images != want {
+		t.Fatalf("Parse() = %#v, want %#v", cfg.Images, want)
+	}
+
+	if _, err := Parse([]byte("images:\n  max-width: wide\n")); err == nil || !strings.Contains(err.Error(), "2: cannot unmarshal !!str `wide` into int") {
+		t.Fatalf("Parse() error = %v, want a number error", err)
+	}
+}
+
+func TestParsePortAndSocket(t *testing.T) {
+	cfg, err := Parse([]byte("port: 7392\nsocket: /run/user/1000/rdm.sock\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if cfg.Port != 7392 || cfg.Socket != "/run/user/1000/rdm.sock" {
+		t.Fatalf("Parse() = port %d, socket %q", cfg.Port, cfg.Socket)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	for _, data := range []string{"", "# nothing yet\n"} {
+		cfg, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", data, err)
+		}
+		if !reflect.DeepEqual(cfg, &Config{}) {
+			t.Fatalf("Parse(%q) = %#v, want empty config", data, cfg)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown key", "commands:\n  cpy: pbcopy\n", "2: unknown setting cpy"},
+		{"unknown top-level key", "port: 7392\nbogus: true\n", "2: unknown setting bogus"},
+		{"list for a scalar", "commands:\n  screenshot-dir: [a, b]\n", "2: cannot unmarshal !!seq into string"},
+		{"tab indentation", "commands:\n\tcopy: pbcopy\n", "2: found character that cannot start any token"},
+		{"not a mapping", "commands\n", "1: cannot unmarshal !!str `commands`"},
+		{"unterminated quote", "commands:\n  copy: sh -c 'echo\n", "2: unterminated quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	got, err := splitArgs(`open -a "Google Chrome" --args 'a b' c\ d "say \"hi\""`)
+	if err != nil {
+		t.Fatalf("splitArgs() error = %v", err)
+	}
+	want := []string{"open", "-a", "Google Chrome", "--args", "a b", "c d", `say "hi"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	cfg, err := LoadFile(filepath.Join(t.TempDir(), "config.yml"))
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, &Config{}) {
+		t.Fatalf("LoadFile() = %#v, want empty config", cfg)
+	}
+}
+
+func TestLoadFileReportsPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("bogus: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFile(path)
+	if err == nil || !strings.HasPrefix(err.Error(), path+":1: unknown setting bogus") {
+		t.Fatalf("LoadFile() error = %v, want it to start with %s:1", err, path)
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Argv is a command line. In the file it is either a list of arguments or a
+// string split like a shell would, honouring quotes and backslashes.
+type Argv []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (a *Argv) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return node.Decode((*[]string)(a))
+	}
+	args, err := splitArgs(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*a = args
+	return nil
+}
+
+// List is a list of values. A single value is a list of one.
+type List []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *List) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return node.Decode((*[]string)(l))
+	}
+	*l = List{node.Value}
+	return nil
+}
+
+var (
+	yamlLine     = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+	unknownField = regexp.MustCompile(`field (\S+) not found in type \S+`)
+)
+
+// yamlError rewrites a decoding error as "line: problem", naming settings
+// rather than Go types.
+func yamlError(err error) error {
+	var problems []string
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		problems = typeErr.Errors
+	} else {
+		problems = []string{err.Error()}
+	}
+	for i, p := range problems {
+		p = yamlLine.ReplaceAllString(p, "$1: ")
+		problems[i] = unknownField.ReplaceAllString(p, "unknown setting $1")
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// splitArgs splits s into words the way a POSIX shell does, without any
+// expansion.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			}
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,165 @@
+package hostservice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardBackend is the set of commands one clipboard tool needs.
+type clipboardBackend struct {
+	name  string
+	tools []string
+	copy  []string
+	paste []string
+	// copyType and pasteType build MIME-typed commands. They are nil for
+	// tools without MIME support.
+	copyType  func(mimeType string) []string
+	pasteType func(mimeType string) []string
+	// encode and decode convert text to and from the tool's encoding. They
+	// are nil for tools that read and write UTF-8.
+	encode func(text string) []byte
+	decode func(data []byte) []byte
+}
+
+var (
+	pbBackend = clipboardBackend{
+		name:  "pbcopy",
+		tools: []string{"pbcopy", "pbpaste"},
+		copy:  []string{"pbcopy"},
+		paste: []string{"pbpaste"},
+	}
+	wlBackend = clipboardBackend{
+		name:  "wl-clipboard",
+		tools: []string{"wl-copy", "wl-paste"},
+		copy:  []string{"wl-copy"},
+		paste: []string{"wl-paste", "--no-newline"},
+		copyType: func(mimeType string) []string {
+			return []string{"wl-copy", "--type", mimeType}
+		},
+		pasteType: func(mimeType string) []string {
+			return []string{"wl-paste", "--no-newline", "--type", mimeType}
+		},
+	}
+	xclipBackend = clipboardBackend{
+		name:  "xclip",
+		tools: []string{"xclip"},
+		copy:  []string{"xclip", "-selection", "clipboard"},
+		paste: []string{"xclip", "-selection", "clipboard", "-o"},
+		copyType: func(mimeType string) []string {
+			return []string{"xclip", "-selection", "clipboard", "-t", mimeType}
+		},
+		pasteType: func(mimeType string) []string {
+			return []string{"xclip", "-selection", "clipboard", "-t", mimeType, "-o"}
+		},
+	}
+	xselBackend = clipboardBackend{
+		name:  "xsel",
+		tools: []string{"xsel"},
+		copy:  []string{"xsel", "--clipboard", "--input"},
+		paste: []string{"xsel", "--clipboard", "--output"},
+	}
+)
+
+// ClipboardBackend names the clipboard tool the service uses, or explains
+// why none is usable.
+func (s *Service) ClipboardBackend() (string, error) {
+	backend, err := s.clipboard()
+	if err != nil {
+		return "", err
+	}
+	return backend.name, nil
+}
+
+// clipboard picks the clipboard backend for this host. On Linux it prefers
+// the Windows clipboard under WSL, wl-clipboard under Wayland and xclip,
+// then xsel, under X11; when neither display variable is set every tool is
+// tried.
+func (s *Service) clipboard() (clipboardBackend, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return pbBackend, nil
+	case "linux":
+	default:
+		return clipboardBackend{}, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+
+	wayland := s.env("WAYLAND_DISPLAY") != ""
+	x11 := s.env("DISPLAY") != ""
+
+	var candidates []clipboardBackend
+	if s.isWSL() {
+		candidates = append(candidates, wslBackend)
+	}
+	if wayland || !x11 {
+		candidates = append(candidates, wlBackend)
+	}
+	if x11 || !wayland {
+		candidates = append(candidates, xclipBackend, xselBackend)
+	}
+
+	var missing []string
+	for _, backend := range candidates {
+		if s.haveTools(backend.tools) {
+			return backend, nil
+		}
+		missing = append(missing, strings.Join(backend.tools, "/"))
+	}
+
+	msg := fmt.Sprintf("no clipboard tool found; install one of: %s", strings.Join(missing, ", "))
+	if !wayland && !x11 {
+		msg += " (neither WAYLAND_DISPLAY nor DISPLAY is set)"
+	}
+	return clipboardBackend{}, fmt.Errorf("%s", msg)
+}
+
+func (s *Service) haveTools(tools []string) bool {
+	for _, tool := range tools {
+		if _, err := s.findTool(tool); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Service) findTool(file string) (string, error) {
+	if s.lookPath != nil {
+		return s.lookPath(file)
+	}
+	return exec.LookPath(file)
+}
+
+func (s *Service) env(key string) string {
+	if s.getenv != nil {
+		return s.getenv(key)
+	}
+	return os.Getenv(key)
+}
+
+// input returns text encoded for the backend's copy command.
+func (b clipboardBackend) input(text string) []byte {
+	if b.encode != nil {
+		return b.encode(text)
+	}
+	return []byte(text)
+}
+
+// output converts the paste command's output to UTF-8 text.
+func (b clipboardBackend) output(data []byte) []byte {
+	if b.decode != nil {
+		return b.decode(data)
+	}
+	return data
+}
+
+// typedCommand returns the backend's command for copying or pasting mimeType.
+func (b clipboardBackend) typedCommand(build func(string) []string, mimeType string) (*exec.Cmd, error) {
+	if build == nil {
+		return nil, fmt.Errorf("%s cannot handle %s; only text/plain is supported: %w", b.name, mimeType, errors.ErrUnsupported)
+	}
+	argv := build(mimeType)
+	return exec.Command(argv[0], argv[1:]...), nil
+}
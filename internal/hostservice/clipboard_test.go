@@ -0,0 +1,94 @@
+package hostservice
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClipboardBackendDetection(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("clipboard backend detection only applies to Linux")
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		tools   []string
+		want    string
+		wantErr string
+	}{
+		{"wayland prefers wl-clipboard", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"wl-copy", "wl-paste", "xclip"}, "wl-clipboard", ""},
+		{"wayland falls back to xwayland xclip", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"xclip"}, "xclip", ""},
+		{"x11 ignores wl-clipboard", map[string]string{"DISPLAY": ":0"}, []string{"wl-copy", "wl-paste", "xsel"}, "xsel", ""},
+		{"x11 prefers xclip", map[string]string{"DISPLAY": ":0"}, []string{"xclip", "xsel"}, "xclip", ""},
+		{"wl-clipboard needs both tools", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"wl-copy"}, "", "wl-copy/wl-paste"},
+		{"nothing installed", map[string]string{"DISPLAY": ":0"}, nil, "", "install one of: xclip, xsel"},
+		{"no display", map[string]string{}, nil, "", "neither WAYLAND_DISPLAY nor DISPLAY is set"},
+		{"wsl prefers the windows clipboard", map[string]string{"WSL_DISTRO_NAME": "Ubuntu", "DISPLAY": ":0"}, []string{"clip.exe", "powershell.exe", "xclip"}, "windows (clip.exe)", ""},
+		{"wsl without interop falls back to xclip", map[string]string{"WSL_DISTRO_NAME": "Ubuntu", "DISPLAY": ":0"}, []string{"xclip"}, "xclip", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &Service{
+				getenv: func(key string) string { return tt.env[key] },
+				lookPath: func(file string) (string, error) {
+					for _, tool := range tt.tools {
+						if tool == file {
+							return "/usr/bin/" + file, nil
+						}
+					}
+					return "", errors.New("not found")
+				},
+				readFile: func(string) ([]byte, error) {
+					return nil, errors.New("not found")
+				},
+			}
+
+			got, err := svc.ClipboardBackend()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ClipboardBackend() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ClipboardBackend() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("ClipboardBackend() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXselRejectsMIMETypes(t *testing.T) {
+	if _, err := xselBackend.typedCommand(xselBackend.copyType, "text/html"); err == nil {
+		t.Fatal("expected xsel to reject typed copies")
+	}
+}
+
+func TestRunClipboardOwnerDoesNotWaitForHelper(t *testing.T) {
+	// Like xclip, leave a child holding stdout and stderr after exiting.
+	cmd := exec.Command("sh", "-c", "sleep 5 & exit 0")
+
+	start := time.Now()
+	if err := runClipboardOwner(cmd, "copy image/png"); err != nil {
+		t.Fatalf("runClipboardOwner() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("runClipboardOwner() waited %s for the background helper", elapsed)
+	}
+}
+
+func TestRunClipboardOwnerReportsStderr(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo 'no display' >&2; exit 1")
+
+	err := runClipboardOwner(cmd, "copy image/png")
+	if err == nil || !strings.Contains(err.Error(), "copy image/png failed") || !strings.Contains(err.Error(), "no display") {
+		t.Fatalf("runClipboardOwner() error = %v, want the command's stderr", err)
+	}
+}
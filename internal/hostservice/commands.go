@@ -0,0 +1,105 @@
+package hostservice
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommandRunner is a Runner that runs user-configured commands for the
+// operations that have one and defers to Runner for the rest.
+type CommandRunner struct {
+	Runner
+
+	// CopyCommand reads the text to copy on stdin.
+	CopyCommand []string
+	// PasteCommand writes the clipboard text to stdout.
+	PasteCommand []string
+	// OpenCommand is run with the target appended as its last argument.
+	OpenCommand []string
+	// ClipboardImageCommand writes the clipboard image as PNG to stdout.
+	ClipboardImageCommand []string
+}
+
+func (r *CommandRunner) Copy(text string) error {
+	if len(r.CopyCommand) == 0 {
+		return r.Runner.Copy(text)
+	}
+	if _, err := runCommand(r.CopyCommand, []byte(text)); err != nil {
+		return fmt.Errorf("copy failed: %w", err)
+	}
+	return nil
+}
+
+func (r *CommandRunner) Paste() ([]byte, error) {
+	if len(r.PasteCommand) == 0 {
+		return r.Runner.Paste()
+	}
+	out, err := runCommand(r.PasteCommand, nil)
+	if err != nil {
+		return nil, fmt.Errorf("paste failed: %w", err)
+	}
+	return out, nil
+}
+
+func (r *CommandRunner) Open(target string) error {
+	if len(r.OpenCommand) == 0 {
+		return r.Runner.Open(target)
+	}
+	argv := append(append([]string(nil), r.OpenCommand...), target)
+	if _, err := runCommand(argv, nil); err != nil {
+		return fmt.Errorf("open failed: %w", err)
+	}
+	return nil
+}
+
+func (r *CommandRunner) ClipboardImage() ([]byte, error) {
+	if len(r.ClipboardImageCommand) == 0 {
+		return r.Runner.ClipboardImage()
+	}
+	out, err := runCommand(r.ClipboardImageCommand, nil)
+	if err != nil {
+		return nil, fmt.Errorf("clipboard image failed: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no image on clipboard")
+	}
+	return out, nil
+}
+
+// CopyType and PasteType send plain text through the configured Copy and
+// Paste commands so history recall and --type text/plain agree with them.
+func (r *CommandRunner) CopyType(mimeType string, data []byte) error {
+	if isPlainText(mimeType) {
+		return r.Copy(string(data))
+	}
+	return r.Runner.CopyType(mimeType, data)
+}
+
+func (r *CommandRunner) PasteType(mimeType string) ([]byte, error) {
+	if isPlainText(mimeType) {
+		return r.Paste()
+	}
+	return r.Runner.PasteType(mimeType)
+}
+
+// runCommand runs argv with stdin and returns its stdout. The error includes
+// anything the command printed to stderr.
+func runCommand(argv []string, stdin []byte) ([]byte, error) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", argv[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", argv[0], err)
+	}
+	return out, nil
+}
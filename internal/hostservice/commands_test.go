@@ -0,0 +1,48 @@
+package hostservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandRunnerUsesConfiguredCommands(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "copied")
+	r := &CommandRunner{
+		CopyCommand:  []string{"sh", "-c", `cat > "$0"`, out},
+		PasteCommand: []string{"printf", "from %s", "paste"},
+		OpenCommand:  []string{"sh", "-c", `printf %s "$1" > "$0"`, out},
+	}
+
+	if err := r.CopyType(TextPlain, []byte("hello")); err != nil {
+		t.Fatalf("CopyType() error = %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "hello" {
+		t.Fatalf("copied %q, want %q", got, "hello")
+	}
+
+	pasted, err := r.Paste()
+	if err != nil {
+		t.Fatalf("Paste() error = %v", err)
+	}
+	if string(pasted) != "from paste" {
+		t.Fatalf("Paste() = %q, want %q", pasted, "from paste")
+	}
+
+	if err := r.Open("https://example.com"); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "https://example.com" {
+		t.Fatalf("opened %q, want %q", got, "https://example.com")
+	}
+}
+
+func TestCommandRunnerReportsStderr(t *testing.T) {
+	r := &CommandRunner{CopyCommand: []string{"sh", "-c", "echo no clipboard >&2; exit 1"}}
+
+	err := r.Copy("hello")
+	if err == nil || !strings.Contains(err.Error(), "no clipboard") {
+		t.Fatalf("Copy() error = %v, want stderr in it", err)
+	}
+}
@@ -0,0 +1,73 @@
+package hostservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Confirm shows a dialog with Open and Deny buttons. It uses osascript on
+// macOS and zenity or kdialog on Linux.
+func (s *Service) Confirm(prompt string, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+5*time.Second)
+	defer cancel()
+
+	seconds := strconv.Itoa(int(timeout.Seconds()))
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "osascript",
+			"-e", "on run argv",
+			"-e", `display dialog (item 1 of argv) with title "gh-rdm" buttons {"Deny", "Open"} default button "Deny" giving up after (item 2 of argv as integer)`,
+			"-e", "return button returned of result",
+			"-e", "end run",
+			prompt, seconds)
+		out, err := cmd.Output()
+		if err != nil {
+			// Exit status 1 means the dialog was cancelled.
+			if isExitCode(err, 1) {
+				return false, nil
+			}
+			return false, fmt.Errorf("osascript failed: %w", err)
+		}
+		return strings.TrimSpace(string(out)) == "Open", nil
+	case "linux":
+		switch {
+		case s.haveTools([]string{"zenity"}):
+			cmd = exec.CommandContext(ctx, "zenity", "--question", "--title=gh-rdm",
+				"--ok-label=Open", "--cancel-label=Deny", "--timeout="+seconds, "--text", prompt)
+		case s.haveTools([]string{"kdialog"}):
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+			cmd = exec.CommandContext(ctx, "kdialog", "--title", "gh-rdm", "--yesno", prompt)
+		default:
+			return false, errors.New("no dialog tool found; install zenity or kdialog")
+		}
+	default:
+		return false, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+
+	err := cmd.Run()
+	switch {
+	case err == nil:
+		return true, nil
+	case ctx.Err() != nil:
+		return false, nil
+	case isExitCode(err, 1), isExitCode(err, 5):
+		// 1 is a "no" answer; zenity exits 5 when it times out.
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s failed: %w", cmd.Args[0], err)
+	}
+}
+
+func isExitCode(err error, code int) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == code
+}
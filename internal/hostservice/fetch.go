@@ -0,0 +1,160 @@
+package hostservice
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotAllowed is returned for paths outside the fetch allowlist.
+var ErrNotAllowed = errors.New("path is outside the allowed directories")
+
+// FileInfo describes a file offered to the remote machine.
+type FileInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir,omitempty"`
+}
+
+// DefaultFetchDirs are the directories remote machines may read from when
+// Service.FetchDirs is empty.
+var DefaultFetchDirs = []string{"~/Downloads", "~/Desktop"}
+
+func (s *Service) FetchFile(path string) (io.ReadCloser, FileInfo, error) {
+	resolved, err := s.resolveAllowed(path)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, FileInfo{}, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, FileInfo{}, fmt.Errorf("%s is a directory", path)
+	}
+
+	return f, fileInfo(info), nil
+}
+
+func (s *Service) ListDir(path string) ([]FileInfo, error) {
+	resolved, err := s.resolveAllowed(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, fileInfo(info))
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+
+	return files, nil
+}
+
+// fetchDirs returns the allowlisted directories with ~ expanded.
+func (s *Service) fetchDirs() ([]string, error) {
+	dirs := s.FetchDirs
+	if len(dirs) == 0 {
+		dirs = DefaultFetchDirs
+	}
+
+	expanded := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		dir, err := expandHome(dir)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, dir)
+	}
+	return expanded, nil
+}
+
+// resolveAllowed resolves path, following symlinks, and checks that the
+// result lies inside an allowlisted directory. Relative paths, and an empty
+// path, are taken relative to the first allowlisted directory.
+func (s *Service) resolveAllowed(path string) (string, error) {
+	roots, err := s.fetchDirs()
+	if err != nil {
+		return "", err
+	}
+
+	target, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(roots[0], target)
+	}
+
+	notAllowed := fmt.Errorf("%w: %s (allowed: %s)", ErrNotAllowed, path, strings.Join(roots, ", "))
+
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(target))
+	if err != nil {
+		// Don't reveal whether files outside the allowlist exist.
+		for _, root := range roots {
+			if isWithin(root, filepath.Clean(target)) {
+				return "", err
+			}
+		}
+		return "", notAllowed
+	}
+
+	for _, root := range roots {
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if isWithin(realRoot, resolved) {
+			return resolved, nil
+		}
+	}
+
+	return "", notAllowed
+}
+
+// isWithin reports whether path is root or lies below it. Both must be clean
+// absolute paths.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func fileInfo(info os.FileInfo) FileInfo {
+	return FileInfo{
+		Name:    info.Name(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}
+}
@@ -1,13 +1,15 @@
 package hostservice
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
-	"sort"
 	"strings"
+	"time"
 )
 
 // Runner defines clipboard and open operations on the host.
@@ -16,11 +18,50 @@ type Runner interface {
 	Paste() ([]byte, error)
 	Open(target string) error
 	LatestScreenshot(dir string) ([]byte, string, error)
+	// Screenshots lists the screenshots in the screenshot directories,
+	// newest first, and OpenScreenshot opens one of them by name.
+	Screenshots() ([]FileInfo, error)
+	OpenScreenshot(name string) (io.ReadCloser, FileInfo, error)
 	ClipboardImage() ([]byte, error)
+	// SaveFile stores r in the inbox under a name derived from name and
+	// returns the path it was written to.
+	SaveFile(name string, r io.Reader) (string, error)
+	// Reveal shows path in the host's file manager.
+	Reveal(path string) error
+	// FetchFile opens path for reading if it lies in an allowlisted
+	// directory, and ListDir lists such a directory. Both return an error
+	// wrapping ErrNotAllowed otherwise.
+	FetchFile(path string) (io.ReadCloser, FileInfo, error)
+	ListDir(path string) ([]FileInfo, error)
+	// Notify shows a desktop notification.
+	Notify(title, message string) error
+	// CopyType and PasteType are Copy and Paste for a specific MIME type,
+	// such as text/html or image/png.
+	CopyType(mimeType string, data []byte) error
+	PasteType(mimeType string) ([]byte, error)
+	// Confirm asks the local user a yes/no question and reports their
+	// answer. No answer within timeout counts as no.
+	Confirm(prompt string, timeout time.Duration) (bool, error)
 }
 
 // Service implements Runner using platform-native commands.
-type Service struct{}
+type Service struct {
+	// InboxDir is where SaveFile writes files. Empty means
+	// ~/Downloads/gh-rdm.
+	InboxDir string
+	// FetchDirs are the directories FetchFile and ListDir may read from.
+	// Empty means DefaultFetchDirs.
+	FetchDirs []string
+	// ScreenshotDir is where LatestScreenshot looks when no directory is
+	// given. Empty means the platform default.
+	ScreenshotDir string
+
+	// lookPath, getenv and readFile default to exec.LookPath, os.Getenv
+	// and os.ReadFile; tests replace them to simulate other hosts.
+	lookPath func(string) (string, error)
+	getenv   func(string) string
+	readFile func(string) ([]byte, error)
+}
 
 // New returns a new Service.
 func New() *Service {
@@ -28,18 +69,13 @@ func New() *Service {
 }
 
 func (s *Service) Copy(text string) error {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = exec.Command("pbcopy")
-	case "linux":
-		cmd = exec.Command("xclip", "-selection", "clipboard")
-	default:
-		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	backend, err := s.clipboard()
+	if err != nil {
+		return err
 	}
 
-	cmd.Stdin = strings.NewReader(text)
+	cmd := exec.Command(backend.copy[0], backend.copy[1:]...)
+	cmd.Stdin = bytes.NewReader(backend.input(text))
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("copy failed: %w", err)
@@ -49,23 +85,18 @@ func (s *Service) Copy(text string) error {
 }
 
 func (s *Service) Paste() ([]byte, error) {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = exec.Command("pbpaste")
-	case "linux":
-		cmd = exec.Command("xclip", "-selection", "clipboard", "-o")
-	default:
-		return nil, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	backend, err := s.clipboard()
+	if err != nil {
+		return nil, err
 	}
 
+	cmd := exec.Command(backend.paste[0], backend.paste[1:]...)
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("paste failed: %w", err)
 	}
 
-	return out, nil
+	return backend.output(out), nil
 }
 
 func (s *Service) Open(target string) error {
@@ -75,7 +106,11 @@ func (s *Service) Open(target string) error {
 	case "darwin":
 		cmd = exec.Command("open", target)
 	case "linux":
-		cmd = exec.Command("xdg-open", target)
+		if s.isWSL() {
+			cmd = s.wslOpenCommand(target)
+		} else {
+			cmd = exec.Command("xdg-open", target)
+		}
 	default:
 		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
@@ -87,99 +122,74 @@ func (s *Service) Open(target string) error {
 	return nil
 }
 
-func (s *Service) LatestScreenshot(dir string) ([]byte, string, error) {
-	if runtime.GOOS != "darwin" {
-		return nil, "", fmt.Errorf("screenshot capture only supported on macOS")
-	}
+func (s *Service) Notify(title, message string) error {
+	var cmd *exec.Cmd
 
-	if dir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, "", fmt.Errorf("get home dir: %w", err)
+	switch runtime.GOOS {
+	case "darwin":
+		// Passing the text as arguments avoids quoting it into the script.
+		cmd = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message)
+	case "linux":
+		if s.isWSL() {
+			if err := s.wslTool("notify-send", "notifications", "libnotify-bin and use WSLg"); err != nil {
+				return err
+			}
 		}
-		dir = filepath.Join(home, "Desktop")
+		cmd = exec.Command("notify-send", "--app-name=gh-rdm", "--", title, message)
+	default:
+		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
 
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, "", fmt.Errorf("read screenshot dir: %w", err)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify failed: %w: %s", err, strings.TrimSpace(string(out)))
 	}
 
-	type fileWithTime struct {
-		name    string
-		modTime int64
-	}
+	return nil
+}
 
-	var screenshots []fileWithTime
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		lower := strings.ToLower(name)
-		if strings.HasPrefix(lower, "screenshot") && strings.HasSuffix(lower, ".png") {
-			info, err := e.Info()
-			if err != nil {
-				continue
-			}
-			screenshots = append(screenshots, fileWithTime{name: name, modTime: info.ModTime().UnixNano()})
-		}
+func (s *Service) LatestScreenshot(dir string) ([]byte, string, error) {
+	screenshots, dirs, err := s.findScreenshots(dir)
+	if err != nil {
+		return nil, "", err
 	}
-
 	if len(screenshots) == 0 {
-		return nil, "", fmt.Errorf("no screenshots found in %s", dir)
+		return nil, "", fmt.Errorf("no screenshots found in %s", strings.Join(dirs, ", "))
 	}
 
-	sort.Slice(screenshots, func(i, j int) bool {
-		return screenshots[i].modTime > screenshots[j].modTime
-	})
-
-	latest := screenshots[0].name
-	data, err := os.ReadFile(filepath.Join(dir, latest))
+	latest := screenshots[0]
+	data, err := os.ReadFile(latest.path)
 	if err != nil {
 		return nil, "", fmt.Errorf("read screenshot: %w", err)
 	}
 
-	return data, latest, nil
+	return data, latest.Name, nil
 }
 
 func (s *Service) ClipboardImage() ([]byte, error) {
-	if runtime.GOOS != "darwin" {
-		return nil, fmt.Errorf("clipboard image capture only supported on macOS")
+	if runtime.GOOS == "linux" {
+		data, err := s.PasteType("image/png")
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, fmt.Errorf("clipboard images are not supported: %w", err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("no image on clipboard: %w", err)
+		}
+		return data, nil
 	}
-
-	tmpFile, err := os.CreateTemp("", "gh-rdm-clipboard-*.png")
-	if err != nil {
-		return nil, fmt.Errorf("create temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	tmpFile.Close()
-	defer os.Remove(tmpPath)
-
-	script := fmt.Sprintf(`try
-	set theImage to the clipboard as «class PNGf»
-	set theFile to open for access POSIX file "%s" with write permission
-	write theImage to theFile
-	close access theFile
-	return "ok"
-on error
-	return "no image"
-end try`, tmpPath)
-
-	cmd := exec.Command("osascript", "-e", script)
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("osascript failed: %w", err)
+	if runtime.GOOS != "darwin" {
+		return nil, fmt.Errorf("clipboard image capture only supported on macOS and Linux")
 	}
 
-	result := strings.TrimSpace(string(out))
-	if result != "ok" {
+	data, err := pastePasteboard(pasteboardClasses["image/png"])
+	if errors.Is(err, errNoClipboardData) {
 		return nil, fmt.Errorf("no image on clipboard")
 	}
-
-	data, err := os.ReadFile(tmpPath)
 	if err != nil {
-		return nil, fmt.Errorf("read clipboard image: %w", err)
+		return nil, err
 	}
 
 	if len(data) == 0 {
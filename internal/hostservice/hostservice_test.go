@@ -1,18 +1,15 @@
 package hostservice
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestLatestScreenshot(t *testing.T) {
-	if runtime.GOOS != "darwin" {
-		t.Skip("screenshot capture only supported on macOS")
-	}
-
 	dir := t.TempDir()
 
 	// Create some fake screenshot files with different timestamps
@@ -57,9 +54,234 @@ func TestLatestScreenshotNoFiles(t *testing.T) {
 	}
 }
 
+func TestLatestScreenshotMatchesLinuxNaming(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"Screenshot from 2026-03-06 10-00-00.png", "Screenshot_20260306_120000.png"} {
+		time.Sleep(20 * time.Millisecond)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, name, err := New().LatestScreenshot(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "Screenshot_20260306_120000.png" {
+		t.Fatalf("expected KDE screenshot to be latest, got %q", name)
+	}
+}
+
+func TestScreenshotsAndOpenScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"Screenshot old.png", "Screenshot new.png", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now, now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := &Service{ScreenshotDir: dir}
+
+	files, err := svc.Screenshots()
+	if err != nil {
+		t.Fatalf("Screenshots() error = %v", err)
+	}
+	if len(files) != 2 || files[0].Name != "Screenshot new.png" || files[1].Name != "Screenshot old.png" {
+		t.Fatalf("Screenshots() = %+v, want newest first without notes.txt", files)
+	}
+
+	body, info, err := svc.OpenScreenshot("Screenshot old.png")
+	if err != nil {
+		t.Fatalf("OpenScreenshot() error = %v", err)
+	}
+	body.Close()
+	if info.Size != int64(len("Screenshot old.png")) {
+		t.Fatalf("OpenScreenshot() size = %d", info.Size)
+	}
+
+	if _, _, err := svc.OpenScreenshot("../notes.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenScreenshot(outside) error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestXDGPicturesDir(t *testing.T) {
+	home := t.TempDir()
+	configHome := filepath.Join(home, ".config")
+	if err := os.MkdirAll(configHome, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	userDirs := "# written by xdg-user-dirs-update\nXDG_DESKTOP_DIR=\"$HOME/Desktop\"\nXDG_PICTURES_DIR=\"$HOME/Bilder\"\n"
+	if err := os.WriteFile(filepath.Join(configHome, "user-dirs.dirs"), []byte(userDirs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"user-dirs.dirs", map[string]string{}, filepath.Join(home, "Bilder")},
+		{"environment", map[string]string{"XDG_PICTURES_DIR": "/srv/pictures"}, "/srv/pictures"},
+		{"fallback", map[string]string{"XDG_CONFIG_HOME": filepath.Join(home, "missing")}, filepath.Join(home, "Pictures")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := xdgPicturesDir(home, func(key string) string { return tt.env[key] })
+			if got != tt.want {
+				t.Fatalf("xdgPicturesDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestLatestScreenshotDefaultDir(t *testing.T) {
 	// When dir is empty, it should use ~/Desktop — just verify it doesn't panic
 	svc := New()
 	// This may return an error if ~/Desktop has no screenshots, but shouldn't panic
 	_, _, _ = svc.LatestScreenshot("")
 }
+
+func TestSaveFileAvoidsCollisions(t *testing.T) {
+	svc := &Service{InboxDir: t.TempDir()}
+
+	first, err := svc.SaveFile("report.pdf", strings.NewReader("one"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := svc.SaveFile("report.pdf", strings.NewReader("two"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if filepath.Base(first) != "report.pdf" {
+		t.Fatalf("expected report.pdf, got %q", first)
+	}
+	if filepath.Base(second) != "report (1).pdf" {
+		t.Fatalf("expected report (1).pdf, got %q", second)
+	}
+	data, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "two" {
+		t.Fatalf("expected content %q, got %q", "two", string(data))
+	}
+}
+
+func TestSaveFileStaysInInbox(t *testing.T) {
+	inbox := t.TempDir()
+	svc := &Service{InboxDir: inbox}
+
+	path, err := svc.SaveFile("../../etc/passwd", strings.NewReader("nope"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(inbox, "passwd") {
+		t.Fatalf("expected file inside inbox, got %q", path)
+	}
+
+	if _, err := svc.SaveFile("..", strings.NewReader("nope")); err == nil {
+		t.Fatal("expected error for invalid name")
+	}
+}
+
+func TestFetchFileAllowlist(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(allowed, "report.pdf"), []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(allowed, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &Service{FetchDirs: []string{allowed}}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantErr     bool
+		wantAllowed bool
+	}{
+		{"absolute inside", filepath.Join(allowed, "report.pdf"), false, true},
+		{"relative to first dir", "report.pdf", false, true},
+		{"outside", filepath.Join(outside, "secret.txt"), true, false},
+		{"dot dot escape", filepath.Join(allowed, "..", filepath.Base(outside), "secret.txt"), true, false},
+		{"relative escape", "../" + filepath.Base(outside) + "/secret.txt", true, false},
+		{"symlink escape", filepath.Join(allowed, "link.txt"), true, false},
+		{"missing outside", "/definitely/not/here", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, _, err := svc.FetchFile(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchFile(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if f != nil {
+				f.Close()
+			}
+			if tt.wantErr && !tt.wantAllowed && !errors.Is(err, ErrNotAllowed) {
+				t.Fatalf("FetchFile(%q) error = %v, want ErrNotAllowed", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestListDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := &Service{FetchDirs: []string{dir}}
+	files, err := svc.ListDir("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 visible files, got %+v", files)
+	}
+
+	if _, err := svc.ListDir("/"); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("expected ErrNotAllowed listing /, got %v", err)
+	}
+}
+
+func TestIsPlainText(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     bool
+	}{
+		{"", true},
+		{"text/plain", true},
+		{"text/plain; charset=utf-8", true},
+		{"text/html", false},
+		{"image/png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			if got := isPlainText(tt.mimeType); got != tt.want {
+				t.Errorf("isPlainText(%q) = %v, want %v", tt.mimeType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasteboardClassRejectsUnknownTypes(t *testing.T) {
+	if class, err := pasteboardClass("text/html"); err != nil || class != "HTML" {
+		t.Fatalf("pasteboardClass(text/html) = %q, %v; want HTML", class, err)
+	}
+	if _, err := pasteboardClass("application/x-unknown"); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+}
@@ -0,0 +1,129 @@
+package hostservice
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxNameAttempts bounds the search for a free name in the inbox.
+const maxNameAttempts = 1000
+
+// Inbox returns the directory SaveFile writes to.
+func (s *Service) Inbox() (string, error) {
+	if s.InboxDir != "" {
+		return expandHome(s.InboxDir)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(home, "Downloads", "gh-rdm"), nil
+}
+
+func (s *Service) SaveFile(name string, r io.Reader) (string, error) {
+	base, err := sanitizeFilename(name)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := s.Inbox()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create inbox: %w", err)
+	}
+
+	f, path, err := CreateUnique(dir, base)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+
+	return path, nil
+}
+
+func (s *Service) Reveal(path string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", "-R", path)
+	case "linux":
+		if s.isWSL() {
+			if err := s.wslTool("wslview", "revealing files", "wslu"); err != nil {
+				return err
+			}
+			cmd = exec.Command("wslview", filepath.Dir(path))
+		} else {
+			cmd = exec.Command("xdg-open", filepath.Dir(path))
+		}
+	default:
+		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("reveal failed: %w", err)
+	}
+
+	return nil
+}
+
+// sanitizeFilename reduces a client-supplied name to a plain file name so it
+// can't escape the inbox.
+func sanitizeFilename(name string) (string, error) {
+	base := filepath.Base(filepath.Clean("/" + strings.ReplaceAll(name, "\\", "/")))
+	if base == "/" || base == "." || base == ".." || strings.TrimSpace(base) == "" {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return base, nil
+}
+
+// CreateUnique creates a new file in dir named base, or "name (n).ext" when
+// that is taken, so existing files are never overwritten.
+func CreateUnique(dir, base string) (*os.File, string, error) {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for i := 0; i < maxNameAttempts; i++ {
+		name := base
+		if i > 0 {
+			name = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			return f, path, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, "", fmt.Errorf("create %s: %w", path, err)
+		}
+	}
+	return nil, "", fmt.Errorf("no free name for %s in %s", base, dir)
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,198 @@
+package hostservice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// TextPlain is the MIME type Copy and Paste handle.
+const TextPlain = "text/plain"
+
+// errNoClipboardData is returned when the clipboard has nothing of the
+// requested type.
+var errNoClipboardData = errors.New("no data of that type on clipboard")
+
+// pasteboardClasses maps MIME types to the AppleScript clipboard classes used
+// on macOS.
+var pasteboardClasses = map[string]string{
+	"text/html":  "HTML",
+	"text/rtf":   "RTF ",
+	"image/png":  "PNGf",
+	"image/tiff": "TIFF",
+	"image/jpeg": "JPEG",
+	"image/gif":  "GIFf",
+}
+
+// CopyType puts data on the clipboard as mimeType.
+func (s *Service) CopyType(mimeType string, data []byte) error {
+	if isPlainText(mimeType) {
+		return s.Copy(string(data))
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		class, err := pasteboardClass(mimeType)
+		if err != nil {
+			return err
+		}
+		return copyPasteboard(class, data)
+	case "linux":
+		backend, err := s.clipboard()
+		if err != nil {
+			return err
+		}
+		cmd, err := backend.typedCommand(backend.copyType, mimeType)
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = bytes.NewReader(data)
+		return runClipboardOwner(cmd, "copy "+mimeType)
+	default:
+		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+}
+
+// runClipboardOwner runs a copy command such as xclip or wl-copy. Both fork
+// a helper that keeps serving the selection with the command's stdout and
+// stderr, so capturing them through a pipe would wait until the selection
+// changes. Stderr goes to a temp file instead, for the error message.
+func runClipboardOwner(cmd *exec.Cmd, action string) error {
+	stderr, err := os.CreateTemp("", "gh-rdm-clipboard-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		msg, _ := os.ReadFile(stderr.Name())
+		return fmt.Errorf("%s failed: %w: %s", action, err, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// PasteType returns the clipboard contents as mimeType.
+func (s *Service) PasteType(mimeType string) ([]byte, error) {
+	if isPlainText(mimeType) {
+		return s.Paste()
+	}
+
+	var data []byte
+	switch runtime.GOOS {
+	case "darwin":
+		class, err := pasteboardClass(mimeType)
+		if err != nil {
+			return nil, err
+		}
+		data, err = pastePasteboard(class)
+		if err != nil {
+			return nil, err
+		}
+	case "linux":
+		backend, err := s.clipboard()
+		if err != nil {
+			return nil, err
+		}
+		cmd, err := backend.typedCommand(backend.pasteType, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("paste %s failed: %w", mimeType, err)
+		}
+		data = out
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: %s", errNoClipboardData, mimeType)
+	}
+	return data, nil
+}
+
+func isPlainText(mimeType string) bool {
+	return mimeType == "" || mimeType == TextPlain || strings.HasPrefix(mimeType, TextPlain+";")
+}
+
+func pasteboardClass(mimeType string) (string, error) {
+	class, ok := pasteboardClasses[mimeType]
+	if !ok {
+		return "", fmt.Errorf("unsupported clipboard type %q on macOS", mimeType)
+	}
+	return class, nil
+}
+
+// copyPasteboard sets the macOS clipboard to data of the given AppleScript
+// class. The data goes through a temp file because it may be too large for
+// a command-line argument.
+func copyPasteboard(class string, data []byte) error {
+	tmpFile, err := os.CreateTemp("", "gh-rdm-clipboard-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	script := fmt.Sprintf(`on run argv
+	set the clipboard to (read (POSIX file (item 1 of argv)) as «class %s»)
+end run`, class)
+
+	cmd := exec.Command("osascript", "-e", script, tmpPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// pastePasteboard reads the macOS clipboard as the given AppleScript class.
+// It returns errNoClipboardData when the clipboard holds no such data.
+func pastePasteboard(class string) ([]byte, error) {
+	tmpFile, err := os.CreateTemp("", "gh-rdm-clipboard-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	script := fmt.Sprintf(`on run argv
+	try
+		set theData to the clipboard as «class %s»
+		set theFile to open for access POSIX file (item 1 of argv) with write permission
+		set eof of theFile to 0
+		write theData to theFile
+		close access theFile
+		return "ok"
+	on error
+		return "no data"
+	end try
+end run`, class)
+
+	cmd := exec.Command("osascript", "-e", script, tmpPath)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("osascript failed: %w", err)
+	}
+	if strings.TrimSpace(string(out)) != "ok" {
+		return nil, errNoClipboardData
+	}
+
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("read clipboard data: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,162 @@
+package hostservice
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// screenshotFile is a screenshot and where it was found.
+type screenshotFile struct {
+	FileInfo
+	path string
+}
+
+func (s *Service) Screenshots() ([]FileInfo, error) {
+	screenshots, _, err := s.findScreenshots("")
+	if err != nil {
+		return nil, err
+	}
+	files := make([]FileInfo, len(screenshots))
+	for i, shot := range screenshots {
+		files[i] = shot.FileInfo
+	}
+	return files, nil
+}
+
+func (s *Service) OpenScreenshot(name string) (io.ReadCloser, FileInfo, error) {
+	screenshots, _, err := s.findScreenshots("")
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	for _, shot := range screenshots {
+		if shot.Name != name {
+			continue
+		}
+		f, err := os.Open(shot.path)
+		if err != nil {
+			return nil, FileInfo{}, fmt.Errorf("open screenshot: %w", err)
+		}
+		return f, shot.FileInfo, nil
+	}
+	return nil, FileInfo{}, fmt.Errorf("screenshot %q: %w", name, os.ErrNotExist)
+}
+
+// findScreenshots lists the screenshots in dir, or in ScreenshotDir or the
+// platform default when dir is empty, newest first. It also returns the
+// directories it searched.
+func (s *Service) findScreenshots(dir string) ([]screenshotFile, []string, error) {
+	if dir == "" {
+		dir = s.ScreenshotDir
+	}
+	dirs, err := screenshotDirs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var screenshots []screenshotFile
+	var readErr error
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			readErr = err
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			name := e.Name()
+			lower := strings.ToLower(name)
+			if !strings.HasPrefix(lower, "screenshot") || !strings.HasSuffix(lower, ".png") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			screenshots = append(screenshots, screenshotFile{
+				FileInfo: FileInfo{Name: name, Size: info.Size(), ModTime: info.ModTime()},
+				path:     filepath.Join(dir, name),
+			})
+		}
+	}
+
+	if len(screenshots) == 0 && readErr != nil && len(dirs) == 1 {
+		return nil, dirs, fmt.Errorf("read screenshot dir: %w", readErr)
+	}
+
+	sort.Slice(screenshots, func(i, j int) bool {
+		return screenshots[i].ModTime.After(screenshots[j].ModTime)
+	})
+	return screenshots, dirs, nil
+}
+
+// screenshotDirs returns the directories to search for screenshots: dir when
+// given, otherwise the platform default. macOS saves to the Desktop; GNOME
+// and KDE save to Pictures/Screenshots, and older GNOME to Pictures itself.
+func screenshotDirs(dir string) ([]string, error) {
+	if dir != "" {
+		expanded, err := expandHome(dir)
+		if err != nil {
+			return nil, err
+		}
+		return []string{expanded}, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("get home dir: %w", err)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{filepath.Join(home, "Desktop")}, nil
+	case "linux":
+		pictures := xdgPicturesDir(home, os.Getenv)
+		return []string{filepath.Join(pictures, "Screenshots"), pictures}, nil
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+}
+
+// xdgPicturesDir returns the user's XDG pictures directory, from
+// XDG_PICTURES_DIR or user-dirs.dirs, falling back to ~/Pictures.
+func xdgPicturesDir(home string, getenv func(string) string) string {
+	if dir := getenv("XDG_PICTURES_DIR"); dir != "" {
+		return expandXDGHome(dir, home)
+	}
+
+	configHome := getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	if f, err := os.Open(filepath.Join(configHome, "user-dirs.dirs")); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+			if !ok || key != "XDG_PICTURES_DIR" {
+				continue
+			}
+			if dir := strings.Trim(value, `"`); dir != "" {
+				return expandXDGHome(dir, home)
+			}
+		}
+	}
+
+	return filepath.Join(home, "Pictures")
+}
+
+// expandXDGHome expands the $HOME prefix user-dirs.dirs entries use.
+func expandXDGHome(dir, home string) string {
+	if rest, ok := strings.CutPrefix(dir, "$HOME"); ok {
+		return filepath.Join(home, rest)
+	}
+	return dir
+}
@@ -0,0 +1,106 @@
+package hostservice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+)
+
+// wslBackend uses the Windows clipboard through WSL interop. clip.exe reads
+// UTF-16LE and Get-Clipboard writes CRLF line endings, so text is converted
+// on the way in and out.
+var wslBackend = clipboardBackend{
+	name:  "windows (clip.exe)",
+	tools: []string{"clip.exe", "powershell.exe"},
+	copy:  []string{"clip.exe"},
+	paste: []string{
+		"powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+		"[Console]::OutputEncoding = New-Object System.Text.UTF8Encoding $false; " +
+			"[Console]::Out.Write((Get-Clipboard -Raw))",
+	},
+	encode: encodeWindowsText,
+	decode: decodeWindowsText,
+}
+
+// isWSL reports whether the service runs inside the Windows Subsystem for
+// Linux.
+func (s *Service) isWSL() bool {
+	if s.env("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	version, err := s.read("/proc/version")
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(version), []byte("microsoft"))
+}
+
+func (s *Service) read(name string) ([]byte, error) {
+	if s.readFile != nil {
+		return s.readFile(name)
+	}
+	return os.ReadFile(name)
+}
+
+// wslOpenCommand opens target with wslview when it is installed, or with the
+// Windows shell otherwise.
+func (s *Service) wslOpenCommand(target string) *exec.Cmd {
+	if _, err := s.findTool("wslview"); err == nil {
+		return exec.Command("wslview", target)
+	}
+	return exec.Command("cmd.exe", "/c", "start", "", escapeCmdArg(target))
+}
+
+// encodeWindowsText converts text to the CRLF, UTF-16LE form clip.exe
+// expects. The byte order mark tells clip.exe which encoding it is reading.
+func encodeWindowsText(text string) []byte {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n", "\r\n")
+
+	units := utf16.Encode([]rune(text))
+	buf := make([]byte, 0, 2+2*len(units))
+	buf = append(buf, 0xff, 0xfe)
+	for _, u := range units {
+		buf = append(buf, byte(u), byte(u>>8))
+	}
+	return buf
+}
+
+// decodeWindowsText turns clipboard text written by PowerShell back into
+// Unix text.
+func decodeWindowsText(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// escapeCmdArg escapes the characters cmd.exe treats specially, such as the
+// & separating URL query parameters. A double quote would switch escaping
+// off, so it is percent-encoded. A caret after each % keeps cmd.exe from
+// expanding %VAR% and is removed before start sees the argument.
+func escapeCmdArg(arg string) string {
+	var b strings.Builder
+	for _, r := range strings.ReplaceAll(arg, `"`, "%22") {
+		switch {
+		case strings.ContainsRune("^&|<>()", r):
+			b.WriteByte('^')
+			b.WriteRune(r)
+		case r == '%':
+			b.WriteString("%^")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// wslTool checks that tool, which action needs under WSL, is installed.
+func (s *Service) wslTool(tool, action, install string) error {
+	if _, err := s.findTool(tool); err != nil {
+		return fmt.Errorf("%s under WSL needs %s; install %s: %w", action, tool, install, errors.ErrUnsupported)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package hostservice
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+)
+
+func TestIsWSL(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		version string
+		want    bool
+	}{
+		{"distro variable", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, "", true},
+		{"wsl2 kernel", nil, "Linux version 5.15.153.1-microsoft-standard-WSL2", true},
+		{"wsl1 kernel", nil, "Linux version 4.4.0-19041-Microsoft", true},
+		{"plain linux", nil, "Linux version 6.8.0-45-generic", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &Service{
+				getenv:   func(key string) string { return tt.env[key] },
+				readFile: func(string) ([]byte, error) { return []byte(tt.version), nil },
+			}
+			if got := svc.isWSL(); got != tt.want {
+				t.Fatalf("isWSL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeWindowsText(t *testing.T) {
+	got := encodeWindowsText("é\nb\r\n")
+	want := []byte{0xff, 0xfe, 0xe9, 0x00, '\r', 0x00, '\n', 0x00, 'b', 0x00, '\r', 0x00, '\n', 0x00}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("encodeWindowsText() = % x, want % x", got, want)
+	}
+}
+
+func TestDecodeWindowsText(t *testing.T) {
+	got := decodeWindowsText([]byte("\xef\xbb\xbfcafé\r\nline two\r\n"))
+	if want := "café\nline two\n"; string(got) != want {
+		t.Fatalf("decodeWindowsText() = %q, want %q", got, want)
+	}
+}
+
+func TestEscapeCmdArg(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/?a=1&b=(2)":      "https://example.com/?a=1^&b=^(2^)",
+		"https://example.com/a%20b?q=%PATH%":  "https://example.com/a%^20b?q=%^PATH%^",
+		`https://example.com/?q="quoted"&x=1`: "https://example.com/?q=%^22quoted%^22^&x=1",
+	}
+	for arg, want := range tests {
+		if got := escapeCmdArg(arg); got != want {
+			t.Errorf("escapeCmdArg(%q) = %q, want %q", arg, got, want)
+		}
+	}
+}
+
+func TestWSLUnsupportedFeatures(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("WSL only applies to Linux")
+	}
+	svc := &Service{
+		getenv: func(key string) string {
+			if key == "WSL_DISTRO_NAME" {
+				return "Ubuntu"
+			}
+			return ""
+		},
+		lookPath: func(file string) (string, error) {
+			if file == "clip.exe" || file == "powershell.exe" {
+				return "/mnt/c/Windows/System32/" + file, nil
+			}
+			return "", exec.ErrNotFound
+		},
+	}
+
+	if _, err := svc.ClipboardImage(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ClipboardImage() error = %v, want unsupported", err)
+	}
+	if err := svc.CopyType("text/html", []byte("<b>hi</b>")); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CopyType() error = %v, want unsupported", err)
+	}
+	if err := svc.Reveal("/home/me/Downloads/gh-rdm/report.pdf"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Reveal() error = %v, want unsupported", err)
+	}
+	if err := svc.Notify("build", "done"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Notify() error = %v, want unsupported", err)
+	}
+}
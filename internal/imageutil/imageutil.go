@@ -0,0 +1,249 @@
+// Package imageutil shrinks and re-encodes images before they are sent
+// through the tunnel.
+package imageutil
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strconv"
+)
+
+// Formats Convert can encode.
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+)
+
+// DefaultQuality is the JPEG quality used when none is given.
+const DefaultQuality = 85
+
+// Keys of the query string Options are sent as.
+const (
+	KeyMaxWidth = "max-width"
+	KeyFormat   = "format"
+	KeyQuality  = "quality"
+)
+
+// Options controls Convert. The zero value leaves images untouched.
+type Options struct {
+	// MaxWidth scales wider images down to this many pixels, keeping the
+	// aspect ratio. Zero means no limit.
+	MaxWidth int
+	// Format is FormatPNG or FormatJPEG. Empty keeps the source format.
+	Format string
+	// Quality is the JPEG quality, 1-100. Zero means DefaultQuality.
+	Quality int
+}
+
+// Validate reports options Convert cannot honour.
+func (o Options) Validate() error {
+	switch o.Format {
+	case "", FormatPNG, FormatJPEG:
+	default:
+		return fmt.Errorf("unsupported image format %q: use %s or %s", o.Format, FormatJPEG, FormatPNG)
+	}
+	if o.MaxWidth < 0 {
+		return fmt.Errorf("invalid max width %d", o.MaxWidth)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("invalid quality %d: use 1-100", o.Quality)
+	}
+	return nil
+}
+
+// Apply returns o with the fields set in query, such as
+// "max-width=1600&format=jpeg", replacing its own.
+func (o Options) Apply(query string) (Options, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return o, fmt.Errorf("invalid image options: %w", err)
+	}
+	for key := range values {
+		value := values.Get(key)
+		switch key {
+		case KeyMaxWidth:
+			o.MaxWidth, err = strconv.Atoi(value)
+		case KeyQuality:
+			o.Quality, err = strconv.Atoi(value)
+		case KeyFormat:
+			o.Format = normalizeFormat(value)
+		default:
+			return o, fmt.Errorf("unknown image option %q", key)
+		}
+		if err != nil {
+			return o, fmt.Errorf("invalid image option %s=%q", key, value)
+		}
+	}
+	return o, o.Validate()
+}
+
+func normalizeFormat(format string) string {
+	if format == "jpg" {
+		return FormatJPEG
+	}
+	return format
+}
+
+// Convert decodes data, scales it down to MaxWidth and encodes it as Format.
+// It returns data unchanged, with its own format, when there is nothing to
+// do. The returned format is the one the result is encoded in.
+func Convert(data []byte, o Options) ([]byte, string, error) {
+	if err := o.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	config, source, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	format := o.Format
+	if format == "" {
+		format = source
+	}
+	resize := o.MaxWidth > 0 && config.Width > o.MaxWidth
+	if !resize && format == source {
+		return data, source, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	rgba := toRGBA(img)
+	if resize {
+		height := max(1, config.Height*o.MaxWidth/config.Width)
+		rgba = Resize(rgba, o.MaxWidth, height)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatPNG:
+		err = png.Encode(&buf, rgba)
+	case FormatJPEG:
+		quality := o.Quality
+		if quality == 0 {
+			quality = DefaultQuality
+		}
+		flattenOnWhite(rgba)
+		err = jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: quality})
+	default:
+		return nil, "", fmt.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("encode %s: %w", format, err)
+	}
+	return buf.Bytes(), format, nil
+}
+
+// Extension returns the file extension for format.
+func Extension(format string) string {
+	if format == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Src)
+	return rgba
+}
+
+// Resize scales src to width x height by averaging the source pixels each
+// destination pixel covers. It is meant for shrinking; enlarging works but
+// only repeats pixels. Sizes below one pixel are raised to one.
+func Resize(src *image.RGBA, width, height int) *image.RGBA {
+	width, height = max(1, width), max(1, height)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+
+	// Scale rows first into a float buffer, then columns into dst.
+	cols := weights(sw, width)
+	rows := weights(sh, height)
+
+	tmp := make([]float32, width*sh*4)
+	for y := 0; y < sh; y++ {
+		line := src.Pix[y*src.Stride:]
+		for x, taps := range cols {
+			var r, g, b, a float32
+			for _, t := range taps {
+				p := line[t.index*4:]
+				r += float32(p[0]) * t.weight
+				g += float32(p[1]) * t.weight
+				b += float32(p[2]) * t.weight
+				a += float32(p[3]) * t.weight
+			}
+			o := (y*width + x) * 4
+			tmp[o], tmp[o+1], tmp[o+2], tmp[o+3] = r, g, b, a
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, taps := range rows {
+		for x := 0; x < width; x++ {
+			var px [4]float32
+			for _, t := range taps {
+				o := (t.index*width + x) * 4
+				for c := range px {
+					px[c] += tmp[o+c] * t.weight
+				}
+			}
+			o := y*dst.Stride + x*4
+			for c, v := range px {
+				dst.Pix[o+c] = uint8(min(255, max(0, v+0.5)))
+			}
+		}
+	}
+	return dst
+}
+
+// tap is one source pixel's share of a destination pixel.
+type tap struct {
+	index  int
+	weight float32
+}
+
+// weights returns, for each of the dst destination pixels along one axis,
+// the source pixels it covers and how much of each, summing to one.
+func weights(src, dst int) [][]tap {
+	scale := float64(src) / float64(dst)
+	all := make([][]tap, dst)
+	for i := range all {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		if scale < 1 {
+			// Enlarging: take the nearest pixel.
+			all[i] = []tap{{index: min(src-1, int(start+scale/2)), weight: 1}}
+			continue
+		}
+		for j := int(start); j < src && float64(j) < end; j++ {
+			overlap := min(end, float64(j+1)) - max(start, float64(j))
+			if overlap > 0 {
+				all[i] = append(all[i], tap{index: j, weight: float32(overlap / scale)})
+			}
+		}
+	}
+	return all
+}
+
+// flattenOnWhite composites img over a white background, since JPEG has no
+// alpha channel and transparent pixels would otherwise turn black.
+func flattenOnWhite(img *image.RGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		if a := img.Pix[i+3]; a != 255 {
+			// Pix is premultiplied, so adding the uncovered share of white
+			// is enough.
+			img.Pix[i] += 255 - a
+			img.Pix[i+1] += 255 - a
+			img.Pix[i+2] += 255 - a
+			img.Pix[i+3] = 255
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package imageutil
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			v := uint8(0)
+			if x%2 == 0 {
+				v = 200
+			}
+			src.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	dst := Resize(src, 2, 1)
+
+	if got := dst.Bounds(); got != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("Resize() bounds = %v", got)
+	}
+	for x := 0; x < 2; x++ {
+		if got := dst.RGBAAt(x, 0); got != (color.RGBA{100, 100, 100, 255}) {
+			t.Fatalf("pixel %d = %v, want the average of black and grey", x, got)
+		}
+	}
+}
+
+func TestResizeNonIntegerScale(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+
+	dst := Resize(src, 2, 2)
+
+	for i, v := range dst.Pix {
+		if v != 255 {
+			t.Fatalf("Pix[%d] = %d, want 255: weights must sum to one", i, v)
+		}
+	}
+}
+
+func TestConvertScalesAndReencodes(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for i := range src.Pix {
+		src.Pix[i] = 128
+	}
+	data := encodePNG(t, src)
+
+	out, format, err := Convert(data, Options{MaxWidth: 200, Format: FormatJPEG, Quality: 70})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if format != FormatJPEG {
+		t.Fatalf("Convert() format = %q, want jpeg", format)
+	}
+	config, decoded, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if decoded != "jpeg" || config.Width != 200 || config.Height != 50 {
+		t.Fatalf("result is %s %dx%d, want jpeg 200x50", decoded, config.Width, config.Height)
+	}
+}
+
+func TestConvertLeavesSmallImagesAlone(t *testing.T) {
+	data := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+
+	out, format, err := Convert(data, Options{MaxWidth: 1600})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if format != FormatPNG || !bytes.Equal(out, data) {
+		t.Fatalf("Convert() changed an image that needed nothing")
+	}
+}
+
+func TestApply(t *testing.T) {
+	defaults := Options{MaxWidth: 1600, Format: FormatJPEG}
+
+	got, err := defaults.Apply("max-width=0&format=jpg&quality=60")
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := (Options{MaxWidth: 0, Format: FormatJPEG, Quality: 60}); got != want {
+		t.Fatalf("Apply() = %+v, want %+v", got, want)
+	}
+
+	if got, _ := defaults.Apply(""); got != defaults {
+		t.Fatalf("Apply(\"\") = %+v, want the defaults", got)
+	}
+
+	for _, query := range []string{"format=gif", "quality=101", "max-width=wide", "scale=2"} {
+		if _, err := defaults.Apply(query); err == nil {
+			t.Fatalf("Apply(%q) error = nil", query)
+		}
+	}
+}
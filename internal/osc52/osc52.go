@@ -0,0 +1,62 @@
+// Package osc52 copies text to the clipboard of the terminal the user is
+// sitting at, using the OSC 52 escape sequence. It works without the gh-rdm
+// tunnel, as long as the terminal supports it.
+package osc52
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MaxBytes is the most text Copy will send. Many terminals silently drop
+// larger sequences.
+const MaxBytes = 100_000
+
+// screenChunk is how much of a sequence fits in one GNU screen passthrough
+// string.
+const screenChunk = 76
+
+// Sequence returns the escape sequence that sets the clipboard to text,
+// wrapped for tmux or GNU screen passthrough when getenv shows the terminal
+// is running inside one.
+func Sequence(text string, getenv func(string) string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+
+	switch {
+	case getenv("TMUX") != "":
+		// tmux forwards DCS passthrough strings with escapes doubled.
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case getenv("STY") != "" || strings.HasPrefix(getenv("TERM"), "screen"):
+		// screen limits the length of a DCS string, so send the sequence
+		// in pieces.
+		var b strings.Builder
+		for len(seq) > 0 {
+			n := min(screenChunk, len(seq))
+			b.WriteString("\x1bP" + seq[:n] + "\x1b\\")
+			seq = seq[n:]
+		}
+		return b.String()
+	default:
+		return seq
+	}
+}
+
+// Copy writes the sequence for text to the controlling terminal.
+func Copy(text string) error {
+	if len(text) > MaxBytes {
+		return fmt.Errorf("%d bytes is too much for OSC 52 (limit %d)", len(text), MaxBytes)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open terminal: %w", err)
+	}
+	defer tty.Close()
+
+	if _, err := tty.WriteString(Sequence(text, os.Getenv)); err != nil {
+		return fmt.Errorf("write to terminal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package osc52
+
+import (
+	"strings"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestSequence(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"plain terminal", map[string]string{"TERM": "xterm-256color"}, "\x1b]52;c;aGk=\a"},
+		{"tmux", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "TERM": "screen"}, "\x1bPtmux;\x1b\x1b]52;c;aGk=\a\x1b\\"},
+		{"screen", map[string]string{"STY": "1234.pts-0.host"}, "\x1bP\x1b]52;c;aGk=\a\x1b\\"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sequence("hi", env(tt.env)); got != tt.want {
+				t.Fatalf("Sequence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequenceSplitsForScreen(t *testing.T) {
+	got := Sequence(strings.Repeat("x", 200), env(map[string]string{"TERM": "screen.xterm-256color"}))
+
+	chunks := strings.Split(strings.TrimSuffix(got, "\x1b\\"), "\x1b\\")
+	if len(chunks) < 2 {
+		t.Fatalf("expected several passthrough chunks, got %q", got)
+	}
+	var joined strings.Builder
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "\x1bP") || len(chunk) > len("\x1bP")+screenChunk {
+			t.Fatalf("bad chunk %q", chunk)
+		}
+		joined.WriteString(strings.TrimPrefix(chunk, "\x1bP"))
+	}
+	if want := Sequence(strings.Repeat("x", 200), env(nil)); joined.String() != want {
+		t.Fatalf("chunks join to %q, want %q", joined.String(), want)
+	}
+}
+
+func TestCopyRejectsLargeText(t *testing.T) {
+	if err := Copy(strings.Repeat("x", MaxBytes+1)); err == nil {
+		t.Fatal("expected an error for text over MaxBytes")
+	}
+}
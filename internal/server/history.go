@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+)
+
+// DefaultHistorySize is how many copies the server remembers by default.
+const DefaultHistorySize = 50
+
+// history is a bounded, newest-first record of copies. It lives in memory
+// unless a file is set, in which case every change is written through.
+type history struct {
+	mu      sync.Mutex
+	limit   int
+	entries []client.HistoryEntry
+	file    string
+}
+
+func newHistory(limit int) *history {
+	return &history{limit: limit}
+}
+
+// add records text, dropping the oldest entry once the limit is reached.
+// Repeating the most recent copy is not recorded twice.
+func (h *history) add(entry client.HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.limit <= 0 {
+		return nil
+	}
+	if len(h.entries) > 0 && h.entries[0].Text == entry.Text {
+		return nil
+	}
+
+	h.entries = append([]client.HistoryEntry{entry}, h.entries...)
+	if len(h.entries) > h.limit {
+		h.entries = h.entries[:h.limit]
+	}
+	return h.save()
+}
+
+// list returns the entries, newest first.
+func (h *history) list() []client.HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]client.HistoryEntry(nil), h.entries...)
+}
+
+// get returns the nth most recent entry, counting from 1.
+func (h *history) get(n int) (client.HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if n < 1 || n > len(h.entries) {
+		return client.HistoryEntry{}, fmt.Errorf("no history entry %d (have %d)", n, len(h.entries))
+	}
+	return h.entries[n-1], nil
+}
+
+func (h *history) setLimit(limit int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.limit = limit
+	if limit < 0 {
+		h.limit = 0
+	}
+	if len(h.entries) > h.limit {
+		h.entries = h.entries[:h.limit]
+	}
+	return h.save()
+}
+
+// persist loads any existing history from file and writes through to it
+// from now on. With history disabled the file is left alone.
+func (h *history) persist(file string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.limit <= 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(file)
+	switch {
+	case err == nil:
+		var entries []client.HistoryEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("parse history file: %w", err)
+		}
+		if len(entries) > h.limit {
+			entries = entries[:h.limit]
+		}
+		h.entries = entries
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("read history file: %w", err)
+	}
+
+	h.file = file
+	return h.save()
+}
+
+// save writes the entries to the history file, if any. Disabling the
+// history keeps the file as it was rather than emptying it. Callers hold mu.
+func (h *history) save() error {
+	if h.file == "" || h.limit <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.file), 0o700); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	data, err := json.Marshal(h.entries)
+	if err != nil {
+		return fmt.Errorf("encode history: %w", err)
+	}
+	if err := os.WriteFile(h.file, data, 0o600); err != nil {
+		return fmt.Errorf("write history file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+)
+
+func TestHistoryKeepsNewestEntries(t *testing.T) {
+	h := newHistory(3)
+	for i := 1; i <= 5; i++ {
+		if err := h.add(client.HistoryEntry{Text: fmt.Sprintf("copy %d", i), Time: time.Now()}); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	entries := h.list()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Text != "copy 5" || entries[2].Text != "copy 3" {
+		t.Fatalf("expected newest first, got %+v", entries)
+	}
+
+	entry, err := h.get(2)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if entry.Text != "copy 4" {
+		t.Fatalf("expected entry 2 to be %q, got %q", "copy 4", entry.Text)
+	}
+	if _, err := h.get(4); err == nil {
+		t.Fatal("expected error for missing entry")
+	}
+}
+
+func TestHistorySkipsRepeatedCopy(t *testing.T) {
+	h := newHistory(3)
+	h.add(client.HistoryEntry{Text: "same"})
+	h.add(client.HistoryEntry{Text: "same"})
+
+	if got := len(h.list()); got != 1 {
+		t.Fatalf("expected 1 entry, got %d", got)
+	}
+}
+
+func TestHistoryStaysInMemoryByDefault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	h := newHistory(3)
+	h.add(client.HistoryEntry{Text: "secret"})
+
+	var files []string
+	filepath.WalkDir(home, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if len(files) != 0 {
+		t.Fatalf("expected nothing written to disk, got %v", files)
+	}
+}
+
+func TestHistoryPersist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.json")
+
+	h := newHistory(3)
+	if err := h.persist(file); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	h.add(client.HistoryEntry{Text: "kept"})
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("stat history file: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("history file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reloaded := newHistory(3)
+	if err := reloaded.persist(file); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	if entries := reloaded.list(); len(entries) != 1 || entries[0].Text != "kept" {
+		t.Fatalf("expected reloaded history, got %+v", entries)
+	}
+}
+
+func TestHistoryDisabledLeavesFileAlone(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.json")
+	want := []byte(`[{"text":"kept","time":"2026-03-06T12:00:00Z"}]`)
+	if err := os.WriteFile(file, want, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	disabled := newHistory(0)
+	if err := disabled.persist(file); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	disabled.add(client.HistoryEntry{Text: "dropped"})
+
+	persisted := newHistory(3)
+	if err := persisted.persist(file); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	if err := persisted.setLimit(0); err != nil {
+		t.Fatalf("setLimit: %v", err)
+	}
+
+	if got, err := os.ReadFile(file); err != nil || string(got) != string(want) {
+		t.Fatalf("history file = %s (%v), want it unchanged", got, err)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Actions an OpenPolicy can take for targets outside its allowlist.
+const (
+	OpenDeny   = "deny"
+	OpenPrompt = "prompt"
+)
+
+// OpenPolicy decides which targets remote open requests may launch.
+type OpenPolicy struct {
+	// Schemes are the URL schemes opened without asking.
+	Schemes []string
+	// Hosts, when set, limits those URLs to hosts matching one of these
+	// path.Match patterns, such as "*.github.com".
+	Hosts []string
+	// Action is OpenDeny or OpenPrompt, for everything else.
+	Action string
+}
+
+// DefaultOpenPolicy opens http and https URLs and denies anything else.
+func DefaultOpenPolicy() OpenPolicy {
+	return OpenPolicy{Schemes: []string{"http", "https"}, Action: OpenDeny}
+}
+
+func (p OpenPolicy) validate() error {
+	switch p.Action {
+	case OpenDeny, OpenPrompt:
+	default:
+		return fmt.Errorf("invalid open action %q: use %q or %q", p.Action, OpenDeny, OpenPrompt)
+	}
+	for _, pattern := range p.Hosts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid open host pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// check returns nil if target is allowed, or the reason it is not.
+func (p OpenPolicy) check(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" {
+		return errors.New("not a URL")
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if !containsFold(p.Schemes, scheme) {
+		return fmt.Errorf("scheme %q is not allowed", scheme)
+	}
+	if len(p.Hosts) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range p.Hosts {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not allowed", host)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+)
+
+func TestOpenPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  OpenPolicy
+		target  string
+		wantErr string
+	}{
+		{"https allowed", DefaultOpenPolicy(), "https://github.com/cli/cli", ""},
+		{"scheme is case-insensitive", DefaultOpenPolicy(), "HTTP://example.com", ""},
+		{"file denied", DefaultOpenPolicy(), "file:///etc/passwd", `scheme "file" is not allowed`},
+		{"app scheme denied", DefaultOpenPolicy(), "vscode://file/tmp/x", `scheme "vscode" is not allowed`},
+		{"local path denied", DefaultOpenPolicy(), "/Applications/Calculator.app", "not a URL"},
+		{"host pattern matches", OpenPolicy{Schemes: []string{"https"}, Hosts: []string{"*.github.com", "github.com"}}, "https://docs.github.com/en", ""},
+		{"host pattern rejects", OpenPolicy{Schemes: []string{"https"}, Hosts: []string{"*.github.com"}}, "https://evil.example/", `host "evil.example" is not allowed`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.check(tt.target)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("check(%q) error = %v", tt.target, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("check(%q) error = %v, want %q", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetOpenPolicyRejectsUnknownAction(t *testing.T) {
+	srv := New(&mockRunner{}, "/tmp/test.sock", log.Default())
+	if err := srv.SetOpenPolicy(OpenPolicy{Schemes: []string{"https"}, Action: "allow"}); err == nil {
+		t.Fatal("expected an error for an unknown action")
+	}
+}
+
+func TestOpenCommandDeniedByPolicy(t *testing.T) {
+	mock := &mockRunner{}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "open", Arguments: []string{"file:///etc/passwd"}})
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `open denied: scheme "file" is not allowed`) {
+		t.Fatalf("unexpected body %q", rec.Body.String())
+	}
+	if mock.openedURL != "" || mock.confirmPrompt != "" {
+		t.Fatalf("expected nothing opened or prompted, got %q / %q", mock.openedURL, mock.confirmPrompt)
+	}
+}
+
+func TestOpenCommandPrompts(t *testing.T) {
+	for _, answer := range []bool{true, false} {
+		mock := &mockRunner{confirmAnswer: answer}
+		srv := New(mock, "/tmp/test.sock", log.Default())
+		policy := DefaultOpenPolicy()
+		policy.Action = OpenPrompt
+		if err := srv.SetOpenPolicy(policy); err != nil {
+			t.Fatal(err)
+		}
+
+		rec := sendCommand(t, srv, client.Command{Name: "open", Arguments: []string{"zoommtg://zoom.us/join"}})
+
+		if mock.confirmPrompt == "" || !strings.Contains(mock.confirmPrompt, "zoommtg://zoom.us/join") {
+			t.Fatalf("expected a prompt naming the target, got %q", mock.confirmPrompt)
+		}
+		if answer {
+			if rec.Code != http.StatusOK || mock.openedURL != "zoommtg://zoom.us/join" {
+				t.Fatalf("expected confirmed open, got %d / %q", rec.Code, mock.openedURL)
+			}
+			continue
+		}
+		if rec.Code != http.StatusForbidden || mock.openedURL != "" {
+			t.Fatalf("expected declined open, got %d / %q", rec.Code, mock.openedURL)
+		}
+		if !strings.Contains(rec.Body.String(), "declined on the local machine") {
+			t.Fatalf("unexpected body %q", rec.Body.String())
+		}
+	}
+}
+
+func TestSendOpenDeniedByPolicy(t *testing.T) {
+	mock := &mockRunner{savedPath: "/Users/me/Downloads/gh-rdm/run.command"}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendStream(t, srv, client.Command{Name: "send", Arguments: []string{"run.command", "open"}}, []byte("#!/bin/sh"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a saved file, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if result := decodeSendResult(t, rec); result.Path != mock.savedPath || !strings.HasPrefix(result.Error, "open denied") {
+		t.Fatalf("expected %s saved with open denied, got %+v", mock.savedPath, result)
+	}
+	if mock.openedURL != "" || mock.confirmPrompt != "" {
+		t.Fatalf("expected nothing opened or prompted, got %q / %q", mock.openedURL, mock.confirmPrompt)
+	}
+}
+
+func TestSendOpenPrompts(t *testing.T) {
+	mock := &mockRunner{savedPath: "/Users/me/Downloads/gh-rdm/report.pdf", confirmAnswer: true}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+	policy := DefaultOpenPolicy()
+	policy.Action = OpenPrompt
+	if err := srv.SetOpenPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := sendStream(t, srv, client.Command{Name: "send", Arguments: []string{"report.pdf", "open"}}, []byte("%PDF"))
+
+	if !strings.Contains(mock.confirmPrompt, mock.savedPath) {
+		t.Fatalf("expected a prompt naming the file, got %q", mock.confirmPrompt)
+	}
+	if rec.Code != http.StatusOK || mock.openedURL != mock.savedPath || decodeSendResult(t, rec).Error != "" {
+		t.Fatalf("expected confirmed open, got %d %s / %q", rec.Code, rec.Body.String(), mock.openedURL)
+	}
+}
+
+func TestSendOpenWaitsForSlowPrompt(t *testing.T) {
+	defer func(d time.Duration) { client.IdleTimeout = d }(client.IdleTimeout)
+	client.IdleTimeout = 50 * time.Millisecond
+
+	mock := &mockRunner{savedPath: "/Users/me/Downloads/gh-rdm/report.pdf", confirmAnswer: true, confirmDelay: 4 * client.IdleTimeout}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+	policy := DefaultOpenPolicy()
+	policy.Action = OpenPrompt
+	if err := srv.SetOpenPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	t.Setenv(client.TokenEnv, srv.Token())
+	c := client.NewWithTCPAddress(ts.Listener.Addr().String())
+	// As gh rdm send --open does, scaled down with IdleTimeout.
+	c.SetTimeout(mock.confirmDelay + client.IdleTimeout)
+	result, err := c.SendStream(context.Background(), "send", strings.NewReader("%PDF"), 4, "report.pdf", "open")
+	if err != nil {
+		t.Fatalf("SendStream() error = %v, want the slow prompt to be waited for", err)
+	}
+	if !strings.Contains(string(result), mock.savedPath) || mock.openedURL != mock.savedPath {
+		t.Fatalf("expected %s saved and opened, got %s / %q", mock.savedPath, result, mock.openedURL)
+	}
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/hostservice"
+	"github.com/maxbeizer/gh-rdm/internal/imageutil"
+)
+
+// serveScreenshots handles the screenshots command:
+//
+//	screenshots [list]          list screenshots as JSON, newest first
+//	screenshots last <n>        send the n newest screenshots
+//	screenshots since <dur>     send screenshots taken in the last dur, e.g. 10m
+//	screenshots name <name>...  send screenshots by file name
+//	screenshots watch           send new screenshots as they are taken
+//
+// Selected screenshots are sent oldest first in one multipart response.
+func (s *Server) serveScreenshots(w http.ResponseWriter, r *http.Request, rc *http.ResponseController, args []string) {
+	all, err := s.host.Screenshots()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("screenshots failed: %v", err), fileErrorStatus(err))
+		return
+	}
+
+	if len(args) == 0 || args[0] == "list" {
+		if all == nil {
+			all = []hostservice.FileInfo{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(all)
+		return
+	}
+
+	opts, err := s.imageOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if args[0] == "watch" {
+		s.watchScreenshots(w, r, rc, all, opts)
+		return
+	}
+
+	selected, err := selectScreenshots(all, args[0], args[1:])
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errNoScreenshots) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	s.streamScreenshots(w, rc, selected, opts)
+}
+
+// errNoScreenshots is returned when a selection matches nothing.
+var errNoScreenshots = errors.New("no matching screenshots")
+
+// selectScreenshots picks screenshots from all, which is newest first, and
+// returns them oldest first.
+func selectScreenshots(all []hostservice.FileInfo, how string, args []string) ([]hostservice.FileInfo, error) {
+	var selected []hostservice.FileInfo
+
+	switch how {
+	case "last":
+		if len(args) != 1 {
+			return nil, errors.New("screenshots last requires a count")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid screenshot count %q", args[0])
+		}
+		selected = all[:min(n, len(all))]
+	case "since":
+		if len(args) != 1 {
+			return nil, errors.New("screenshots since requires a duration")
+		}
+		// A duration rather than a time keeps the remote's clock out of it.
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration %q", args[0])
+		}
+		since := time.Now().Add(-d)
+		for _, f := range all {
+			if f.ModTime.After(since) {
+				selected = append(selected, f)
+			}
+		}
+	case "name":
+		if len(args) == 0 {
+			return nil, errors.New("screenshots name requires at least one name")
+		}
+		for _, name := range args {
+			found := false
+			for _, f := range all {
+				if f.Name == name {
+					selected = append(selected, f)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%w: %q", errNoScreenshots, name)
+			}
+		}
+		// Names are sent in the order given.
+		return selected, nil
+	default:
+		return nil, fmt.Errorf("unknown screenshots action %q", how)
+	}
+
+	if len(selected) == 0 {
+		return nil, errNoScreenshots
+	}
+
+	oldestFirst := make([]hostservice.FileInfo, len(selected))
+	for i, f := range selected {
+		oldestFirst[len(selected)-1-i] = f
+	}
+	return oldestFirst, nil
+}
+
+// streamScreenshots sends files as a multipart/mixed response, one part per
+// file.
+func (s *Server) streamScreenshots(w http.ResponseWriter, rc *http.ResponseController, files []hostservice.FileInfo, opts imageutil.Options) {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", mime.FormatMediaType(client.MultipartContentType, map[string]string{"boundary": mw.Boundary()}))
+
+	for _, f := range files {
+		if err := s.writeScreenshotPart(mw, rc, f.Name, opts); err != nil {
+			// The status is already sent; a truncated body tells the
+			// client the transfer failed.
+			s.logger.Printf("streaming screenshot %s: %v", f.Name, err)
+			return
+		}
+	}
+	if err := mw.Close(); err != nil {
+		s.logger.Printf("streaming screenshots: %v", err)
+	}
+}
+
+// How often watchScreenshots looks for new files, and how long it lets the
+// connection go quiet before sending a heartbeat. Tests shorten them.
+var (
+	watchInterval     = time.Second
+	heartbeatInterval = client.IdleTimeout / 2
+)
+
+// watchScreenshots sends each screenshot taken after existing until the
+// client disconnects or the server shuts down. Files are sent once their size
+// stops changing, so half-written screenshots aren't picked up. Heartbeat
+// parts keep both ends' idle timeouts from firing while nothing happens.
+func (s *Server) watchScreenshots(w http.ResponseWriter, r *http.Request, rc *http.ResponseController, existing []hostservice.FileInfo, opts imageutil.Options) {
+	seen := make(map[string]bool, len(existing))
+	for _, f := range existing {
+		seen[f.Name] = true
+	}
+	pending := map[string]int64{}
+
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", mime.FormatMediaType(client.MultipartContentType, map[string]string{"boundary": mw.Boundary()}))
+	if err := s.writeHeartbeat(mw, rc); err != nil {
+		return
+	}
+	s.logger.Printf("watching screenshots for %s", r.Header.Get(client.SessionHeader))
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	lastWrite := time.Now()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			s.logger.Printf("stopped watching screenshots for %s", r.Header.Get(client.SessionHeader))
+			return
+		case <-s.shutdown.Done():
+			s.logger.Printf("stopped watching screenshots for %s: server shutting down", r.Header.Get(client.SessionHeader))
+			return
+		case <-ticker.C:
+		}
+
+		files, err := s.host.Screenshots()
+		if err != nil {
+			s.logger.Printf("watching screenshots: %v", err)
+		}
+		// Oldest first, so a burst arrives in the order it was taken.
+		for i := len(files) - 1; i >= 0; i-- {
+			f := files[i]
+			if seen[f.Name] {
+				continue
+			}
+			if size, ok := pending[f.Name]; !ok || size != f.Size {
+				pending[f.Name] = f.Size
+				continue
+			}
+			delete(pending, f.Name)
+			seen[f.Name] = true
+
+			if err := s.writeScreenshotPart(mw, rc, f.Name, opts); err != nil {
+				s.logger.Printf("streaming screenshot %s: %v", f.Name, err)
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			lastWrite = time.Now()
+		}
+
+		if time.Since(lastWrite) >= heartbeatInterval {
+			if err := s.writeHeartbeat(mw, rc); err != nil {
+				return
+			}
+			lastWrite = time.Now()
+		}
+	}
+}
+
+// writeHeartbeat sends an empty part the client skips, and flushes it.
+func (s *Server) writeHeartbeat(mw *multipart.Writer, rc *http.ResponseController) error {
+	header := textproto.MIMEHeader{}
+	header.Set(client.HeartbeatHeader, "1")
+	if _, err := mw.CreatePart(header); err != nil {
+		return err
+	}
+	extendDeadlines(rc)
+	return rc.Flush()
+}
+
+// writeScreenshotPart sends the screenshot called name as the next part,
+// converted with opts.
+func (s *Server) writeScreenshotPart(mw *multipart.Writer, rc *http.ResponseController, name string, opts imageutil.Options) error {
+	body, info, err := s.host.OpenScreenshot(name)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	header := textproto.MIMEHeader{}
+	var src io.Reader = body
+	size := info.Size
+	if opts != (imageutil.Options{}) {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		converted, newName, changed, err := convertImage(data, name, opts)
+		if err != nil {
+			return err
+		}
+		if changed {
+			header.Set(client.OriginalSizeHeader, strconv.Itoa(len(data)))
+		}
+		name, src, size = newName, bytes.NewReader(converted), int64(len(converted))
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = client.StreamContentType
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	header.Set("Content-Length", strconv.FormatInt(size, 10))
+	header.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	return copyWithProgress(part, src, rc)
+}
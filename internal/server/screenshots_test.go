@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/hostservice"
+	"github.com/maxbeizer/gh-rdm/internal/imageutil"
+)
+
+func testScreenshots() []hostservice.FileInfo {
+	now := time.Now()
+	return []hostservice.FileInfo{
+		{Name: "Screenshot 3.png", Size: 20, ModTime: now.Add(-time.Minute)},
+		{Name: "Screenshot 2.png", Size: 20, ModTime: now.Add(-5 * time.Minute)},
+		{Name: "Screenshot 1.png", Size: 20, ModTime: now.Add(-time.Hour)},
+	}
+}
+
+func TestScreenshotsList(t *testing.T) {
+	srv := New(&mockRunner{screenshots: testScreenshots()}, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "screenshots", Arguments: []string{"list"}})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var files []hostservice.FileInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &files); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(files) != 3 || files[0].Name != "Screenshot 3.png" {
+		t.Fatalf("unexpected listing %+v", files)
+	}
+}
+
+func TestScreenshotsSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"last", []string{"last", "2"}, []string{"Screenshot 2.png", "Screenshot 3.png"}},
+		{"last more than exist", []string{"last", "10"}, []string{"Screenshot 1.png", "Screenshot 2.png", "Screenshot 3.png"}},
+		{"since", []string{"since", "10m"}, []string{"Screenshot 2.png", "Screenshot 3.png"}},
+		{"name keeps order", []string{"name", "Screenshot 3.png", "Screenshot 1.png"}, []string{"Screenshot 3.png", "Screenshot 1.png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(&mockRunner{screenshots: testScreenshots()}, "/tmp/test.sock", log.Default())
+
+			rec := sendCommand(t, srv, client.Command{Name: "screenshots", Arguments: tt.args})
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+			if err != nil || mediaType != client.MultipartContentType {
+				t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+			}
+
+			var got []string
+			mr := multipart.NewReader(rec.Body, params["boundary"])
+			for {
+				part, err := mr.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("reading part: %v", err)
+				}
+				data, _ := io.ReadAll(part)
+				if string(data) != "png:"+part.FileName() {
+					t.Fatalf("part %s body = %q", part.FileName(), data)
+				}
+				got = append(got, part.FileName())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScreenshotsSelectionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"unknown name", []string{"name", "missing.png"}, http.StatusNotFound},
+		{"nothing recent", []string{"since", "10s"}, http.StatusNotFound},
+		{"bad count", []string{"last", "0"}, http.StatusBadRequest},
+		{"bad duration", []string{"since", "yesterday"}, http.StatusBadRequest},
+		{"unknown action", []string{"first", "1"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(&mockRunner{screenshots: testScreenshots()}, "/tmp/test.sock", log.Default())
+
+			rec := sendCommand(t, srv, client.Command{Name: "screenshots", Arguments: tt.args})
+
+			if rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// watchRunner lets a test add screenshots while the server watches.
+type watchRunner struct {
+	mockRunner
+	mu     sync.Mutex
+	listed int
+}
+
+func (w *watchRunner) Screenshots() ([]hostservice.FileInfo, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listed++
+	return append([]hostservice.FileInfo(nil), w.screenshots...), nil
+}
+
+func (w *watchRunner) OpenScreenshot(name string) (io.ReadCloser, hostservice.FileInfo, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mockRunner.OpenScreenshot(name)
+}
+
+func (w *watchRunner) add(f hostservice.FileInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.screenshots = append([]hostservice.FileInfo{f}, w.screenshots...)
+}
+
+func TestScreenshotsWatch(t *testing.T) {
+	oldWatch, oldHeartbeat := watchInterval, heartbeatInterval
+	watchInterval, heartbeatInterval = 5*time.Millisecond, 20*time.Millisecond
+	defer func() { watchInterval, heartbeatInterval = oldWatch, oldHeartbeat }()
+
+	runner := &watchRunner{mockRunner: mockRunner{screenshots: testScreenshots()}}
+	srv := New(runner, "/tmp/test.sock", log.Default())
+
+	body, err := json.Marshal(client.Command{Name: "screenshots", Arguments: []string{"watch"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+srv.Token())
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		srv.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	runner.add(hostservice.FileInfo{Name: "Screenshot 4.png", Size: 20, ModTime: time.Now()})
+	time.Sleep(80 * time.Millisecond)
+	cancel()
+	<-done
+
+	_, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	var files []string
+	heartbeats := 0
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			// The watch ends without a closing boundary.
+			break
+		}
+		if part.Header.Get(client.HeartbeatHeader) != "" {
+			heartbeats++
+			continue
+		}
+		files = append(files, part.FileName())
+	}
+	if len(files) != 1 || files[0] != "Screenshot 4.png" {
+		t.Fatalf("watched files = %v, want only the new screenshot", files)
+	}
+	if heartbeats < 2 {
+		t.Fatalf("got %d heartbeats, want at least 2", heartbeats)
+	}
+}
+
+func TestScreenshotConvertsImages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockRunner{screenshotData: buf.Bytes(), screenshotName: "Screenshot.png"}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+	if err := srv.SetImageDefaults(imageutil.Options{MaxWidth: 32}); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(client.Command{Name: "screenshot"})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+srv.Token())
+	req.Header.Set("Accept", client.StreamContentType)
+	req.Header.Set(client.ImageHeader, "format=jpeg")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(client.OriginalSizeHeader); got != strconv.Itoa(buf.Len()) {
+		t.Fatalf("original size header = %q, want %d", got, buf.Len())
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "Screenshot.jpg") {
+		t.Fatalf("Content-Disposition = %q, want a .jpg name", rec.Header().Get("Content-Disposition"))
+	}
+	config, format, err := image.DecodeConfig(rec.Body)
+	if err != nil || format != "jpeg" || config.Width != 32 {
+		t.Fatalf("got %s %dx%d (%v), want a 32px wide jpeg", format, config.Width, config.Height, err)
+	}
+}
+
+func TestScreenshotRejectsBadImageOptions(t *testing.T) {
+	srv := New(&mockRunner{screenshotData: []byte("png"), screenshotName: "s.png"}, "/tmp/test.sock", log.Default())
+
+	body, _ := json.Marshal(client.Command{Name: "screenshot"})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+srv.Token())
+	req.Header.Set(client.ImageHeader, "format=webp")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStopEndsScreenshotWatch(t *testing.T) {
+	oldWatch := watchInterval
+	watchInterval = 5 * time.Millisecond
+	defer func() { watchInterval = oldWatch }()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(client.TokenEnv, "")
+
+	path := filepath.Join(t.TempDir(), "gh-rdm.sock")
+	runner := &watchRunner{mockRunner: mockRunner{screenshots: testScreenshots()}}
+	srv := New(runner, path, log.New(io.Discard, "", 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- srv.Listen(ctx) }()
+	for deadline := time.Now().Add(5 * time.Second); client.ReadToken(client.SocketTokenKey(path)) != srv.Token(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not start")
+		}
+	}
+
+	// The watcher outlives the server's context, like a remote client does.
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go client.NewWithSocketPath(path).FetchFiles(watchCtx, "screenshots", func(*client.Stream) error { return nil }, "watch")
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		runner.mu.Lock()
+		watching := runner.listed > 1
+		runner.mu.Unlock()
+		if watching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watch did not start")
+		}
+	}
+
+	start := time.Now()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Listen() error = %v with a watcher attached, want a clean shutdown", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown took %s with a watcher attached", elapsed)
+	}
+}
@@ -1,69 +1,168 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
 	"github.com/maxbeizer/gh-rdm/internal/hostservice"
+	"github.com/maxbeizer/gh-rdm/internal/imageutil"
+	"github.com/maxbeizer/gh-rdm/internal/version"
 )
 
+// maxClipboardBytes caps typed clipboard payloads, which are held in memory.
+const maxClipboardBytes = 64 << 20
+
+// commands lists every command ServeHTTP dispatches, advertised by status.
+var commands = []string{
+	"status",
+	"copy",
+	"paste",
+	"open",
+	"screenshot",
+	"screenshots",
+	"clipboard-image",
+	"send",
+	"fetch",
+	"list",
+	"notify",
+	"history",
+	"stop",
+}
+
+// features lists the optional protocol features this server implements.
+var features = []string{
+	client.FeatureTokenAuth,
+	client.FeatureStream,
+	client.FeatureMIMEClipboard,
+	client.FeatureScreenshotSelect,
+	client.FeatureScreenshotWatch,
+	client.FeatureImageConvert,
+}
+
 // Server handles host-service commands over a unix socket.
 type Server struct {
 	host       hostservice.Runner
 	path       string
+	token      string
+	history    *history
+	openPolicy OpenPolicy
+	images     imageutil.Options
 	logger     *log.Logger
 	httpServer *http.Server
 	cancel     context.CancelFunc
+	// shutdown is cancelled when the HTTP server starts shutting down, to
+	// end responses that would otherwise run until the client leaves.
+	shutdown     context.Context
+	stopWatching context.CancelFunc
 }
 
-// New creates a Server with sensible defaults.
+// New creates a Server with sensible defaults and a freshly minted session
+// token.
 func New(service hostservice.Runner, path string, logger *log.Logger) *Server {
 	s := &Server{
-		host:   service,
-		path:   path,
-		logger: logger,
+		host:       service,
+		path:       path,
+		token:      client.NewToken(),
+		history:    newHistory(DefaultHistorySize),
+		openPolicy: DefaultOpenPolicy(),
+		logger:     logger,
 	}
 
+	// Deadlines are set per request and extended as data moves (see
+	// extendDeadlines), so large transfers are bounded by progress rather
+	// than total duration.
 	s.httpServer = &http.Server{
-		Handler:      s,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Handler:           s,
+		ReadHeaderTimeout: client.IdleTimeout,
+		IdleTimeout:       client.IdleTimeout,
 	}
+	s.shutdown, s.stopWatching = context.WithCancel(context.Background())
+	s.httpServer.RegisterOnShutdown(s.stopWatching)
 
 	return s
 }
 
+// SetHistoryLimit sets how many copies the server remembers. Zero disables
+// the history.
+func (s *Server) SetHistoryLimit(n int) error {
+	return s.history.setLimit(n)
+}
+
+// PersistHistory keeps the clipboard history in file across restarts. By
+// default it is only held in memory.
+func (s *Server) PersistHistory(file string) error {
+	return s.history.persist(file)
+}
+
+// SetOpenPolicy sets which targets remote open requests may launch. The
+// default is DefaultOpenPolicy.
+func (s *Server) SetOpenPolicy(p OpenPolicy) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	s.openPolicy = p
+	return nil
+}
+
+// SetImageDefaults sets how screenshots and clipboard images are resized
+// and encoded when the client doesn't say. By default they are sent as is.
+func (s *Server) SetImageDefaults(o imageutil.Options) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	s.images = o
+	return nil
+}
+
+// Token returns the session token clients must present.
+func (s *Server) Token() string {
+	return s.token
+}
+
 // ServeHTTP dispatches incoming commands.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("read body: %v", err), http.StatusBadRequest)
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized: missing or invalid gh-rdm token", http.StatusUnauthorized)
 		return
 	}
 
-	var cmd client.Command
-	if err := json.Unmarshal(body, &cmd); err != nil {
-		http.Error(w, fmt.Sprintf("parse command: %v", err), http.StatusBadRequest)
+	rc := http.NewResponseController(w)
+	extendDeadlines(rc)
+
+	cmd, payload, err := readCommand(r, rc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	switch cmd.Name {
 	case "status":
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, `{"status": "running"}`)
+		json.NewEncoder(w).Encode(s.status())
 
 	case "copy":
+		if payload != nil {
+			s.copyTyped(w, r, cmd.Arguments, payload)
+			return
+		}
 		if len(cmd.Arguments) < 1 {
 			http.Error(w, "copy requires an argument", http.StatusBadRequest)
 			return
@@ -72,9 +171,19 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("copy failed: %v", err), http.StatusInternalServerError)
 			return
 		}
+		s.recordCopy(r, cmd.Arguments[0])
 		w.WriteHeader(http.StatusOK)
 
 	case "paste":
+		if len(cmd.Arguments) > 0 && cmd.Arguments[0] != hostservice.TextPlain {
+			data, err := s.host.PasteType(cmd.Arguments[0])
+			if err != nil {
+				http.Error(w, fmt.Sprintf("paste failed: %v", err), http.StatusInternalServerError)
+				return
+			}
+			s.streamFile(w, rc, "clipboard"+extensionFor(cmd.Arguments[0]), bytes.NewReader(data), int64(len(data)))
+			return
+		}
 		data, err := s.host.Paste()
 		if err != nil {
 			http.Error(w, fmt.Sprintf("paste failed: %v", err), http.StatusInternalServerError)
@@ -87,6 +196,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "open requires an argument", http.StatusBadRequest)
 			return
 		}
+		if err := s.allowOpen(r, rc, cmd.Arguments[0]); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if err := s.host.Open(cmd.Arguments[0]); err != nil {
 			http.Error(w, fmt.Sprintf("open failed: %v", err), http.StatusInternalServerError)
 			return
@@ -103,15 +216,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("screenshot failed: %v", err), http.StatusInternalServerError)
 			return
 		}
-		resp := struct {
-			Filename string `json:"filename"`
-			Data     string `json:"data"`
-		}{
-			Filename: filename,
-			Data:     base64.StdEncoding.EncodeToString(data),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		s.writeImage(w, r, rc, filename, data)
+
+	case "screenshots":
+		s.serveScreenshots(w, r, rc, cmd.Arguments)
 
 	case "clipboard-image":
 		data, err := s.host.ClipboardImage()
@@ -119,15 +227,83 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("clipboard-image failed: %v", err), http.StatusInternalServerError)
 			return
 		}
-		resp := struct {
-			Filename string `json:"filename"`
-			Data     string `json:"data"`
-		}{
-			Filename: "clipboard.png",
-			Data:     base64.StdEncoding.EncodeToString(data),
+		s.writeImage(w, r, rc, "clipboard.png", data)
+
+	case "send":
+		if payload == nil || len(cmd.Arguments) < 1 {
+			http.Error(w, "send requires a filename and a streamed body", http.StatusBadRequest)
+			return
+		}
+		path, err := s.host.SaveFile(cmd.Arguments[0], payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("send failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.logger.Printf("received %s", path)
+		// The file is saved, so a failed reveal or open is reported
+		// alongside its path rather than failing the request.
+		result := client.SendResult{Path: path}
+		if len(cmd.Arguments) > 1 {
+			switch cmd.Arguments[1] {
+			case "reveal":
+				if err := s.host.Reveal(path); err != nil {
+					result.Error = fmt.Sprintf("reveal failed: %v", err)
+				}
+			case "open":
+				// A received file could be a script, so opening it is up
+				// to the open policy like any other target.
+				if err := s.allowOpen(r, rc, path); err != nil {
+					result.Error = err.Error()
+				} else if err := s.host.Open(path); err != nil {
+					result.Error = fmt.Sprintf("open failed: %v", err)
+				}
+			}
+			if result.Error != "" {
+				s.logger.Printf("%s %s: %s", cmd.Arguments[1], path, result.Error)
+			}
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		json.NewEncoder(w).Encode(result)
+
+	case "fetch":
+		if len(cmd.Arguments) < 1 {
+			http.Error(w, "fetch requires a path", http.StatusBadRequest)
+			return
+		}
+		f, info, err := s.host.FetchFile(cmd.Arguments[0])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("fetch failed: %v", err), fileErrorStatus(err))
+			return
+		}
+		defer f.Close()
+		s.streamFile(w, rc, info.Name, f, info.Size)
+
+	case "list":
+		var dir string
+		if len(cmd.Arguments) > 0 {
+			dir = cmd.Arguments[0]
+		}
+		files, err := s.host.ListDir(dir)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("list failed: %v", err), fileErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(files)
+
+	case "notify":
+		if len(cmd.Arguments) < 2 {
+			http.Error(w, "notify requires a title and a message", http.StatusBadRequest)
+			return
+		}
+		if err := s.host.Notify(cmd.Arguments[0], cmd.Arguments[1]); err != nil {
+			http.Error(w, fmt.Sprintf("notify failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	case "history":
+		s.serveHistory(w, r, cmd.Arguments)
 
 	case "stop":
 		if s.cancel != nil {
@@ -140,17 +316,314 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Listen creates the unix socket and starts serving.
+// copyTyped puts a streamed payload on the clipboard as the MIME type in
+// args, defaulting to plain text.
+func (s *Server) copyTyped(w http.ResponseWriter, r *http.Request, args []string, payload io.Reader) {
+	mimeType := hostservice.TextPlain
+	if len(args) > 0 && args[0] != "" {
+		mimeType = args[0]
+	}
+
+	data, err := io.ReadAll(io.LimitReader(payload, maxClipboardBytes+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read payload: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxClipboardBytes {
+		http.Error(w, fmt.Sprintf("clipboard payload exceeds %d bytes", maxClipboardBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := s.host.CopyType(mimeType, data); err != nil {
+		http.Error(w, fmt.Sprintf("copy failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if mimeType == hostservice.TextPlain {
+		s.recordCopy(r, string(data))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// extensionFor returns a file extension for mimeType, or "" if unknown.
+func extensionFor(mimeType string) string {
+	switch mimeType {
+	case "text/html":
+		return ".html"
+	case "text/rtf":
+		return ".rtf"
+	}
+	exts, err := mime.ExtensionsByType(mimeType)
+	if err != nil || len(exts) == 0 {
+		return ""
+	}
+	return exts[0]
+}
+
+// serveHistory lists the clipboard history, or with "get <n>" or "copy <n>"
+// returns or re-copies a single entry.
+func (s *Server) serveHistory(w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.history.list())
+		return
+	}
+
+	if len(args) != 2 || (args[0] != "get" && args[0] != "copy") {
+		http.Error(w, "history takes no arguments, or get <n> or copy <n>", http.StatusBadRequest)
+		return
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid history entry %q", args[1]), http.StatusBadRequest)
+		return
+	}
+	entry, err := s.history.get(n)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if args[0] == "copy" {
+		if err := s.host.Copy(entry.Text); err != nil {
+			http.Error(w, fmt.Sprintf("copy failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.recordCopy(r, entry.Text)
+	}
+	fmt.Fprint(w, entry.Text)
+}
+
+// recordCopy adds text to the clipboard history.
+func (s *Server) recordCopy(r *http.Request, text string) {
+	entry := client.HistoryEntry{
+		Text:    text,
+		Time:    time.Now(),
+		Session: r.Header.Get(client.SessionHeader),
+	}
+	if err := s.history.add(entry); err != nil {
+		s.logger.Printf("record clipboard history: %v", err)
+	}
+}
+
+func (s *Server) status() client.Status {
+	return client.Status{
+		Status:          "running",
+		Version:         version.Version,
+		ProtocolVersion: client.ProtocolVersion,
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		Commands:        commands,
+		Features:        features,
+	}
+}
+
+// readCommand decodes the command in r. JSON requests carry the command as
+// their body; streamed requests carry it in client.CommandHeader and return
+// the body as payload, which extends the connection deadlines as it is read.
+func readCommand(r *http.Request, rc *http.ResponseController) (client.Command, io.Reader, error) {
+	var cmd client.Command
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == client.StreamContentType {
+		if err := json.Unmarshal([]byte(r.Header.Get(client.CommandHeader)), &cmd); err != nil {
+			return cmd, nil, fmt.Errorf("parse command: %w", err)
+		}
+		return cmd, &deadlineReader{r: r.Body, rc: rc}, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return cmd, nil, fmt.Errorf("read body: %w", err)
+	}
+	if err := json.Unmarshal(body, &cmd); err != nil {
+		return cmd, nil, fmt.Errorf("parse command: %w", err)
+	}
+	return cmd, nil, nil
+}
+
+// deadlineReader extends the connection deadlines whenever data arrives.
+type deadlineReader struct {
+	r  io.Reader
+	rc *http.ResponseController
+}
+
+func (d *deadlineReader) Read(b []byte) (int, error) {
+	n, err := d.r.Read(b)
+	if n > 0 {
+		extendDeadlines(d.rc)
+	}
+	return n, err
+}
+
+// writeImage converts an image as the server defaults and the request's
+// image options ask, then sends it with writeFile. When the image changes,
+// the response reports its original size.
+func (s *Server) writeImage(w http.ResponseWriter, r *http.Request, rc *http.ResponseController, filename string, data []byte) {
+	opts, err := s.imageOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	converted, filename, changed, err := convertImage(data, filename, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if changed {
+		w.Header().Set(client.OriginalSizeHeader, strconv.Itoa(len(data)))
+	}
+	s.writeFile(w, r, rc, filename, converted)
+}
+
+// imageOptions returns the server's image defaults overridden by the
+// request's ImageHeader.
+func (s *Server) imageOptions(r *http.Request) (imageutil.Options, error) {
+	return s.images.Apply(r.Header.Get(client.ImageHeader))
+}
+
+// convertImage converts data with opts, renaming filename to match the new
+// format, and reports whether anything changed.
+func convertImage(data []byte, filename string, opts imageutil.Options) ([]byte, string, bool, error) {
+	if opts == (imageutil.Options{}) {
+		return data, filename, false, nil
+	}
+	converted, format, err := imageutil.Convert(data, opts)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("convert image: %w", err)
+	}
+	if len(converted) == len(data) && bytes.Equal(converted, data) {
+		return data, filename, false, nil
+	}
+	if ext := imageutil.Extension(format); !strings.EqualFold(filepath.Ext(filename), ext) {
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + ext
+	}
+	return converted, filename, true, nil
+}
+
+// writeFile sends data as a raw stream when the client accepts one, and in
+// the base64 JSON form older clients expect otherwise.
+func (s *Server) writeFile(w http.ResponseWriter, r *http.Request, rc *http.ResponseController, filename string, data []byte) {
+	if !strings.Contains(r.Header.Get("Accept"), client.StreamContentType) {
+		resp := struct {
+			Filename string `json:"filename"`
+			Data     string `json:"data"`
+		}{
+			Filename: filename,
+			Data:     base64.StdEncoding.EncodeToString(data),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	s.streamFile(w, rc, filename, bytes.NewReader(data), int64(len(data)))
+}
+
+// streamFile sends body as a raw stream named filename. size may be -1 when
+// unknown.
+func (s *Server) streamFile(w http.ResponseWriter, rc *http.ResponseController, filename string, body io.Reader, size int64) {
+	w.Header().Set("Content-Type", client.StreamContentType)
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	if err := copyWithProgress(w, body, rc); err != nil {
+		s.logger.Printf("streaming %s: %v", filename, err)
+	}
+}
+
+// fileErrorStatus maps a host file error to an HTTP status.
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, hostservice.ErrNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// copyWithProgress copies src to w in chunks, extending the connection
+// deadlines after each one.
+func copyWithProgress(w io.Writer, src io.Reader, rc *http.ResponseController) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			extendDeadlines(rc)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// allowOpen applies the open policy to target, asking the local user when
+// the policy says to prompt.
+func (s *Server) allowOpen(r *http.Request, rc *http.ResponseController, target string) error {
+	reason := s.openPolicy.check(target)
+	if reason == nil {
+		return nil
+	}
+
+	if s.openPolicy.Action == OpenPrompt {
+		session := r.Header.Get(client.SessionHeader)
+		if session == "" {
+			session = "A remote session"
+		}
+		ok, err := s.host.Confirm(fmt.Sprintf("%s wants to open %s", session, target), client.PromptTimeout)
+		extendDeadlines(rc)
+		switch {
+		case err != nil:
+			s.logger.Printf("open %q: could not ask for confirmation: %v", target, err)
+			return fmt.Errorf("open denied: %v, and asking for confirmation failed: %v", reason, err)
+		case ok:
+			return nil
+		}
+		s.logger.Printf("open %q declined", target)
+		return fmt.Errorf("open denied: %v, and it was declined on the local machine", reason)
+	}
+
+	s.logger.Printf("open %q denied: %v", target, reason)
+	return fmt.Errorf("open denied: %v", reason)
+}
+
+// extendDeadlines gives the connection another IdleTimeout to make progress.
+// Writers that don't support deadlines, such as test recorders, are ignored.
+func extendDeadlines(rc *http.ResponseController) {
+	deadline := time.Now().Add(client.IdleTimeout)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
+
+// Listen creates the unix socket and starts serving. The default socket
+// directory is created private; a socket placed elsewhere with --socket
+// lives wherever the user put it, with the socket itself still 0600.
 func (s *Server) Listen(ctx context.Context) error {
+	if dir := filepath.Dir(s.path); dir == client.SocketDir() {
+		if err := privateDir(dir, os.Getuid()); err != nil {
+			return err
+		}
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	s.cancel = cancel
 
 	ln, err := net.Listen("unix", s.path)
 	if err != nil {
 		if isAddrInUse(err) {
-			// Check if existing socket is alive.
+			// Check if existing socket is alive. Any response, even a
+			// rejected token, means a server is listening; only a failed
+			// connection makes the socket stale.
 			c := client.NewWithSocketPath(s.path)
-			if _, statusErr := c.SendCommand(ctx, "status"); statusErr == nil {
+			if _, statusErr := c.SendCommand(ctx, "status"); !client.IsUnreachable(statusErr) {
 				cancel()
 				return fmt.Errorf("server already running at %s", s.path)
 			}
@@ -171,11 +644,33 @@ func (s *Server) Listen(ctx context.Context) error {
 		}
 	}
 
+	// The token already guards every command; keep other users from even
+	// connecting.
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		ln.Close()
+		cancel()
+		return fmt.Errorf("restrict socket permissions: %w", err)
+	}
+
+	if err := client.WriteToken(client.SocketTokenKey(s.path), s.token); err != nil {
+		ln.Close()
+		cancel()
+		return fmt.Errorf("write session token: %w", err)
+	}
+
 	return s.Serve(ctx, ln)
 }
 
-// Serve starts the HTTP server and blocks until ctx is cancelled.
+// Serve starts the HTTP server and blocks until ctx is cancelled. On the way
+// out it removes the session token file, so nothing hands a dead server's
+// token to the remote side.
 func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	defer func() {
+		if err := client.RemoveToken(client.SocketTokenKey(s.path), s.token); err != nil {
+			s.logger.Printf("remove session token: %v", err)
+		}
+	}()
+
 	errCh := make(chan error, 1)
 
 	go func() {
@@ -198,6 +693,15 @@ func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
 	}
 }
 
+// authorized reports whether r carries the session token.
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
 func isAddrInUse(err error) bool {
 	var opErr *net.OpError
 	if errors.As(err, &opErr) {
@@ -2,14 +2,23 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/maxbeizer/gh-rdm/internal/client"
+	"github.com/maxbeizer/gh-rdm/internal/hostservice"
 )
 
 // mockRunner records calls and returns configured values.
@@ -25,6 +34,31 @@ type mockRunner struct {
 	screenshotErr  error
 	clipboardImg   []byte
 	clipboardErr   error
+	savedName      string
+	savedData      []byte
+	savedPath      string
+	saveErr        error
+	revealedPath   string
+	revealErr      error
+	fetchedPath    string
+	fetchData      []byte
+	fetchErr       error
+	listedDir      string
+	listFiles      []hostservice.FileInfo
+	listErr        error
+	notifyTitle    string
+	notifyMessage  string
+	notifyErr      error
+	copiedType     string
+	copiedData     []byte
+	pastedType     string
+	pasteTypeData  []byte
+	confirmPrompt  string
+	confirmAnswer  bool
+	confirmErr     error
+	confirmDelay   time.Duration
+	screenshots    []hostservice.FileInfo
+	screenshotsErr error
 }
 
 func (m *mockRunner) Copy(text string) error {
@@ -45,11 +79,81 @@ func (m *mockRunner) LatestScreenshot(dir string) ([]byte, string, error) {
 	return m.screenshotData, m.screenshotName, m.screenshotErr
 }
 
+func (m *mockRunner) Screenshots() ([]hostservice.FileInfo, error) {
+	return m.screenshots, m.screenshotsErr
+}
+
+func (m *mockRunner) OpenScreenshot(name string) (io.ReadCloser, hostservice.FileInfo, error) {
+	for _, f := range m.screenshots {
+		if f.Name == name {
+			return io.NopCloser(strings.NewReader("png:" + name)), f, nil
+		}
+	}
+	return nil, hostservice.FileInfo{}, os.ErrNotExist
+}
+
 func (m *mockRunner) ClipboardImage() ([]byte, error) {
 	return m.clipboardImg, m.clipboardErr
 }
 
-func sendCommand(t *testing.T, srv http.Handler, cmd client.Command) *httptest.ResponseRecorder {
+func (m *mockRunner) SaveFile(name string, r io.Reader) (string, error) {
+	m.savedName = name
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	m.savedData = data
+	return m.savedPath, m.saveErr
+}
+
+func (m *mockRunner) Reveal(path string) error {
+	m.revealedPath = path
+	return m.revealErr
+}
+
+func (m *mockRunner) FetchFile(path string) (io.ReadCloser, hostservice.FileInfo, error) {
+	m.fetchedPath = path
+	if m.fetchErr != nil {
+		return nil, hostservice.FileInfo{}, m.fetchErr
+	}
+	info := hostservice.FileInfo{Name: "report.pdf", Size: int64(len(m.fetchData))}
+	return io.NopCloser(bytes.NewReader(m.fetchData)), info, nil
+}
+
+func (m *mockRunner) CopyType(mimeType string, data []byte) error {
+	m.copiedType = mimeType
+	m.copiedData = data
+	return m.copyErr
+}
+
+func (m *mockRunner) PasteType(mimeType string) ([]byte, error) {
+	m.pastedType = mimeType
+	return m.pasteTypeData, m.pasteErr
+}
+
+func (m *mockRunner) Notify(title, message string) error {
+	m.notifyTitle = title
+	m.notifyMessage = message
+	return m.notifyErr
+}
+
+func (m *mockRunner) Confirm(prompt string, timeout time.Duration) (bool, error) {
+	m.confirmPrompt = prompt
+	time.Sleep(m.confirmDelay)
+	return m.confirmAnswer, m.confirmErr
+}
+
+func (m *mockRunner) ListDir(path string) ([]hostservice.FileInfo, error) {
+	m.listedDir = path
+	return m.listFiles, m.listErr
+}
+
+func sendCommand(t *testing.T, srv *Server, cmd client.Command) *httptest.ResponseRecorder {
+	t.Helper()
+	return sendCommandWithToken(t, srv, cmd, srv.Token())
+}
+
+func sendCommandWithToken(t *testing.T, srv http.Handler, cmd client.Command, token string) *httptest.ResponseRecorder {
 	t.Helper()
 
 	body, err := json.Marshal(cmd)
@@ -59,6 +163,9 @@ func sendCommand(t *testing.T, srv http.Handler, cmd client.Command) *httptest.R
 
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	rec := httptest.NewRecorder()
 	srv.ServeHTTP(rec, req)
 
@@ -117,12 +224,39 @@ func TestStatusCommand(t *testing.T) {
 		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
 	}
 
-	var status map[string]string
+	var status client.Status
 	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
 		t.Fatalf("unmarshal status: %v", err)
 	}
-	if status["status"] != "running" {
-		t.Fatalf("expected status %q, got %q", "running", status["status"])
+	if status.Status != "running" {
+		t.Fatalf("expected status %q, got %q", "running", status.Status)
+	}
+	if status.ProtocolVersion != client.ProtocolVersion {
+		t.Fatalf("expected protocol %d, got %d", client.ProtocolVersion, status.ProtocolVersion)
+	}
+	if status.OS == "" || status.Version == "" {
+		t.Fatalf("expected os and version in status, got %+v", status)
+	}
+	for _, name := range []string{"copy", "paste", "open", "screenshot", "clipboard-image", "stop"} {
+		if !status.Supports(name) {
+			t.Fatalf("expected status to advertise %q, got %v", name, status.Commands)
+		}
+	}
+	for _, feature := range []string{client.FeatureStream, client.FeatureScreenshotSelect, client.FeatureScreenshotWatch, client.FeatureImageConvert} {
+		if !status.HasFeature(feature) {
+			t.Fatalf("expected status to advertise %q, got %v", feature, status.Features)
+		}
+	}
+}
+
+func TestStatusAdvertisesEveryCommand(t *testing.T) {
+	srv := New(&mockRunner{}, "/tmp/test.sock", log.Default())
+
+	for _, name := range commands {
+		rec := sendCommand(t, srv, client.Command{Name: name})
+		if rec.Code == http.StatusBadRequest && strings.HasPrefix(rec.Body.String(), "unknown command") {
+			t.Errorf("advertised command %q is not handled", name)
+		}
 	}
 }
 
@@ -194,6 +328,258 @@ func TestClipboardImageCommand(t *testing.T) {
 	}
 }
 
+func TestScreenshotCommandStreamsWhenAccepted(t *testing.T) {
+	imgData := bytes.Repeat([]byte("png"), 50000)
+	mock := &mockRunner{screenshotData: imgData, screenshotName: "Screenshot 2026-03-06.png"}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	body, _ := json.Marshal(client.Command{Name: "screenshot"})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+srv.Token())
+	req.Header.Set("Accept", client.StreamContentType)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != client.StreamContentType {
+		t.Fatalf("expected content type %q, got %q", client.StreamContentType, got)
+	}
+	if got := rec.Header().Get("Content-Length"); got != "150000" {
+		t.Fatalf("expected content length %q, got %q", "150000", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "Screenshot 2026-03-06.png") {
+		t.Fatalf("expected filename in content disposition, got %q", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), imgData) {
+		t.Fatal("expected raw image bytes in body")
+	}
+}
+
+func sendStream(t *testing.T, srv *Server, cmd client.Command, payload []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	header, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("marshal command: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", client.StreamContentType)
+	req.Header.Set(client.CommandHeader, string(header))
+	req.Header.Set("Authorization", "Bearer "+srv.Token())
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestSendCommand(t *testing.T) {
+	mock := &mockRunner{savedPath: "/Users/me/Downloads/gh-rdm/build.log"}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendStream(t, srv, client.Command{Name: "send", Arguments: []string{"build.log", "reveal"}}, []byte("log output"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.savedName != "build.log" || string(mock.savedData) != "log output" {
+		t.Fatalf("expected build.log with payload, got %q %q", mock.savedName, mock.savedData)
+	}
+	if result := decodeSendResult(t, rec); result != (client.SendResult{Path: mock.savedPath}) {
+		t.Fatalf("expected saved path %q, got %+v", mock.savedPath, result)
+	}
+	if mock.revealedPath != mock.savedPath {
+		t.Fatalf("expected %q to be revealed, got %q", mock.savedPath, mock.revealedPath)
+	}
+}
+
+func TestSendCommandReportsFailedReveal(t *testing.T) {
+	mock := &mockRunner{savedPath: "/Users/me/Downloads/gh-rdm/build.log", revealErr: errors.New("no file manager")}
+	srv := New(mock, "/tmp/test.sock", log.New(io.Discard, "", 0))
+
+	rec := sendStream(t, srv, client.Command{Name: "send", Arguments: []string{"build.log", "reveal"}}, []byte("log output"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a saved file, got %d: %s", rec.Code, rec.Body.String())
+	}
+	want := client.SendResult{Path: mock.savedPath, Error: "reveal failed: no file manager"}
+	if result := decodeSendResult(t, rec); result != want {
+		t.Fatalf("expected %+v, got %+v", want, result)
+	}
+}
+
+func decodeSendResult(t *testing.T, rec *httptest.ResponseRecorder) client.SendResult {
+	t.Helper()
+	var result client.SendResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode send result %q: %v", rec.Body.String(), err)
+	}
+	return result
+}
+
+func TestSendCommandRequiresPayload(t *testing.T) {
+	mock := &mockRunner{}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "send", Arguments: []string{"build.log"}})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestFetchCommandStreamsFile(t *testing.T) {
+	mock := &mockRunner{fetchData: []byte("%PDF")}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "fetch", Arguments: []string{"~/Downloads/report.pdf"}})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.fetchedPath != "~/Downloads/report.pdf" {
+		t.Fatalf("expected fetch of %q, got %q", "~/Downloads/report.pdf", mock.fetchedPath)
+	}
+	if rec.Body.String() != "%PDF" {
+		t.Fatalf("expected file body, got %q", rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "report.pdf") {
+		t.Fatalf("expected filename in content disposition, got %q", got)
+	}
+}
+
+func TestFetchCommandRefusesDisallowedPath(t *testing.T) {
+	mock := &mockRunner{fetchErr: fmt.Errorf("%w: /etc/passwd", hostservice.ErrNotAllowed)}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "fetch", Arguments: []string{"/etc/passwd"}})
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	mock := &mockRunner{listFiles: []hostservice.FileInfo{{Name: "report.pdf", Size: 4}}}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "list", Arguments: []string{"~/Downloads"}})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var files []hostservice.FileInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &files); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "report.pdf" {
+		t.Fatalf("expected report.pdf listing, got %+v", files)
+	}
+}
+
+func TestNotifyCommand(t *testing.T) {
+	mock := &mockRunner{}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "notify", Arguments: []string{"build", "tests passed"}})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.notifyTitle != "build" || mock.notifyMessage != "tests passed" {
+		t.Fatalf("expected notification %q/%q, got %q/%q", "build", "tests passed", mock.notifyTitle, mock.notifyMessage)
+	}
+}
+
+func TestNotifyCommandRequiresMessage(t *testing.T) {
+	srv := New(&mockRunner{}, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "notify", Arguments: []string{"build"}})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHistoryCommands(t *testing.T) {
+	mock := &mockRunner{}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	for _, text := range []string{"first", "second"} {
+		body, _ := json.Marshal(client.Command{Name: "copy", Arguments: []string{text}})
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+srv.Token())
+		req.Header.Set(client.SessionHeader, "shiny-space")
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := sendCommand(t, srv, client.Command{Name: "history"})
+	var entries []client.HistoryEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("unmarshal history: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Text != "second" || entries[0].Session != "shiny-space" {
+		t.Fatalf("expected newest entry from shiny-space, got %+v", entries)
+	}
+
+	rec = sendCommand(t, srv, client.Command{Name: "history", Arguments: []string{"get", "2"}})
+	if rec.Body.String() != "first" {
+		t.Fatalf("expected entry 2 to be %q, got %q", "first", rec.Body.String())
+	}
+
+	rec = sendCommand(t, srv, client.Command{Name: "history", Arguments: []string{"copy", "2"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.copiedText != "first" {
+		t.Fatalf("expected %q to be copied again, got %q", "first", mock.copiedText)
+	}
+
+	rec = sendCommand(t, srv, client.Command{Name: "history", Arguments: []string{"get", "9"}})
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestCopyCommandWithMIMEType(t *testing.T) {
+	mock := &mockRunner{}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendStream(t, srv, client.Command{Name: "copy", Arguments: []string{"text/html"}}, []byte("<table></table>"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.copiedType != "text/html" || string(mock.copiedData) != "<table></table>" {
+		t.Fatalf("expected html copy, got %q %q", mock.copiedType, mock.copiedData)
+	}
+	if entries := srv.history.list(); len(entries) != 0 {
+		t.Fatalf("expected rich copies to stay out of the text history, got %+v", entries)
+	}
+}
+
+func TestPasteCommandWithMIMEType(t *testing.T) {
+	mock := &mockRunner{pasteTypeData: []byte("\x89PNG")}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommand(t, srv, client.Command{Name: "paste", Arguments: []string{"image/png"}})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.pastedType != "image/png" {
+		t.Fatalf("expected image/png paste, got %q", mock.pastedType)
+	}
+	if got := rec.Header().Get("Content-Type"); got != client.StreamContentType {
+		t.Fatalf("expected content type %q, got %q", client.StreamContentType, got)
+	}
+	if rec.Body.String() != "\x89PNG" {
+		t.Fatalf("expected png bytes, got %q", rec.Body.String())
+	}
+}
+
 func TestClipboardImageCommandError(t *testing.T) {
 	mock := &mockRunner{clipboardErr: fmt.Errorf("no image on clipboard")}
 	srv := New(mock, "/tmp/test.sock", log.Default())
@@ -204,3 +590,94 @@ func TestClipboardImageCommandError(t *testing.T) {
 		t.Fatalf("expected 500, got %d", rec.Code)
 	}
 }
+
+func TestRejectsMissingToken(t *testing.T) {
+	mock := &mockRunner{}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommandWithToken(t, srv, client.Command{Name: "copy", Arguments: []string{"secret"}}, "")
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+	if mock.copiedText != "" {
+		t.Fatalf("expected copy to be rejected, got %q", mock.copiedText)
+	}
+}
+
+func TestRejectsWrongToken(t *testing.T) {
+	mock := &mockRunner{pasteData: []byte("clipboard content")}
+	srv := New(mock, "/tmp/test.sock", log.Default())
+
+	rec := sendCommandWithToken(t, srv, client.Command{Name: "paste"}, "not-the-token")
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+	if bytes.Contains(rec.Body.Bytes(), []byte("clipboard content")) {
+		t.Fatal("expected clipboard content to be withheld")
+	}
+}
+
+func TestNewMintsDistinctTokens(t *testing.T) {
+	first := New(&mockRunner{}, "/tmp/test.sock", log.Default())
+	second := New(&mockRunner{}, "/tmp/test.sock", log.Default())
+
+	if first.Token() == "" {
+		t.Fatal("expected a session token")
+	}
+	if first.Token() == second.Token() {
+		t.Fatal("expected each server to mint its own token")
+	}
+}
+
+func TestListenKeepsServerThatRejectsToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(client.TokenEnv, "some-other-token")
+	path := filepath.Join(t.TempDir(), "gh-rdm.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running := New(&mockRunner{}, path, log.New(io.Discard, "", 0))
+	go running.Serve(ctx, ln)
+
+	// Should Listen take the socket over, it serves until the timeout.
+	listenCtx, listenCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer listenCancel()
+	err = New(&mockRunner{}, path, log.New(io.Discard, "", 0)).Listen(listenCtx)
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("Listen() error = %v, want already running", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("running server's socket was removed: %v", err)
+	}
+}
+
+func TestServeRemovesTokenOnShutdown(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(client.TokenEnv, "")
+	path := filepath.Join(t.TempDir(), "gh-rdm.sock")
+	key := client.SocketTokenKey(path)
+	srv := New(&mockRunner{}, path, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Listen(ctx) }()
+	for deadline := time.Now().Add(5 * time.Second); client.ReadToken(key) != srv.Token(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not write its session token")
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	if got := client.ReadToken(key); got != "" {
+		t.Fatalf("session token %q left behind after shutdown", got)
+	}
+}
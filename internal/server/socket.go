@@ -0,0 +1,39 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// privateDir makes sure dir exists, belongs to uid and is accessible by
+// that user only, creating it or tightening its mode as needed. A directory
+// someone else owns could let them replace or eavesdrop on the socket, so it
+// is refused.
+func privateDir(dir string, uid int) error {
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("create socket directory: %w", err)
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("socket directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("socket directory %s: cannot determine owner", dir)
+	}
+	if int(stat.Uid) != uid {
+		return fmt.Errorf("socket directory %s is owned by uid %d, not you (uid %d); remove it or choose another socket with --socket", dir, stat.Uid, uid)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return fmt.Errorf("restrict socket directory: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxbeizer/gh-rdm/internal/client"
+)
+
+func TestPrivateDirCreatesOwnerOnlyDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gh-rdm")
+
+	if err := privateDir(dir, os.Getuid()); err != nil {
+		t.Fatalf("privateDir() error = %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Fatalf("directory mode = %o, want 700", perm)
+	}
+}
+
+func TestPrivateDirTightensExistingDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := privateDir(dir, os.Getuid()); err != nil {
+		t.Fatalf("privateDir() error = %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Fatalf("directory mode = %o, want 700", perm)
+	}
+}
+
+func TestPrivateDirRefusesOtherOwner(t *testing.T) {
+	if err := privateDir(t.TempDir(), os.Getuid()+1); err == nil {
+		t.Fatal("privateDir() error = nil for a directory owned by someone else")
+	}
+}
+
+func TestPrivateDirRefusesSymlink(t *testing.T) {
+	root := t.TempDir()
+	link := filepath.Join(root, "gh-rdm")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := privateDir(link, os.Getuid()); err == nil {
+		t.Fatal("privateDir() error = nil for a symlink")
+	}
+}
+
+func TestListenCreatesPrivateSocket(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	path := client.UnixSocketPath()
+
+	listenUntilSocket(t, path)
+
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Fatalf("socket directory mode = %o, want 700", perm)
+	}
+}
+
+func TestListenLeavesCustomSocketDirAlone(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	listenUntilSocket(t, filepath.Join(dir, "work.sock"))
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o755 {
+		t.Fatalf("custom socket directory mode = %o, want it left at 755", perm)
+	}
+}
+
+// listenUntilSocket starts a server on path, waits for a 0600 socket to
+// appear and stops the server when the test ends.
+func listenUntilSocket(t *testing.T, path string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- New(&mockRunner{}, path, log.New(os.Stderr, "", 0)).Listen(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	var info os.FileInfo
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var err error
+		if info, err = os.Stat(path); err == nil && info.Mode().Perm() == 0o600 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("socket not created with mode 600: %v", err)
+		}
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Fatalf("%s is not a socket", path)
+	}
+}
@@ -0,0 +1,6 @@
+// Package version reports the gh-rdm build version.
+package version
+
+// Version is the gh-rdm release, set at build time with
+// -ldflags "-X github.com/maxbeizer/gh-rdm/internal/version.Version=...".
+var Version = "dev"
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
@@ -30,6 +31,10 @@ func main() {
 	}()
 
 	if err := cmd.Execute(ctx, userMessages); err != nil {
+		var exitErr *cmd.ExitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		userMessages.Printf("error: %v", err)
 		os.Exit(1)
 	}